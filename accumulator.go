@@ -0,0 +1,99 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrPlaintextOverflow = errors.New("okamoto-uchiyama: plain text could exceed the plaintext bound")
+var ErrNoPlaintextBound = errors.New("okamoto-uchiyama: public key has no plaintext bound")
+
+// Accumulator maintains a homomorphic running sum of ciphertexts under a
+// public key, i.e. the product of all added ciphertexts mod N. It is not safe
+// for concurrent use; goroutines sharing one must synchronize access.
+type Accumulator struct {
+	pub *PublicKey
+	sum *big.Int
+
+	// bound is an upper bound on the plain text sum of the terms added
+	// through AddChecked.
+	bound *big.Int
+}
+
+// NewAccumulator returns an accumulator whose running sum starts from a
+// fresh encryption of zero.
+func (pub *PublicKey) NewAccumulator() (*Accumulator, error) {
+	zero, err := pub.EncryptZero()
+	if err != nil {
+		return nil, err
+	}
+	return &Accumulator{
+		pub: pub,
+		sum: new(big.Int).SetBytes(zero),
+
+		bound: new(big.Int),
+	}, nil
+}
+
+// Add homomorphically adds the passed cipher to the running sum. It returns
+// an error if cipher value is larger than modulus N of Public key.
+func (a *Accumulator) Add(c []byte) error {
+	cipher, err := a.pub.cipherInt(c)
+	if err != nil {
+		return err
+	}
+
+	// S = S * c mod N
+	a.sum.Mod(
+		new(big.Int).Mul(a.sum, cipher),
+		a.pub.N,
+	)
+	return nil
+}
+
+// AddChecked adds the passed cipher like Add, given that its plain text is at
+// most termBound. It tracks an upper bound on the accumulated plain text and
+// returns ErrPlaintextOverflow, without adding, if the sum could reach the
+// PlaintextBound of the public key, beyond which decryption would wrap.
+// Terms added through Add are not counted in the tracked bound.
+func (a *Accumulator) AddChecked(c []byte, termBound *big.Int) error {
+	if a.pub.PlaintextBound == nil {
+		return ErrNoPlaintextBound
+	}
+	if termBound.Sign() < 0 {
+		return ErrInvalidScalar
+	}
+
+	bound := new(big.Int).Add(a.bound, termBound)
+	if bound.Cmp(a.pub.PlaintextBound) >= 0 {
+		return ErrPlaintextOverflow
+	}
+	if err := a.Add(c); err != nil {
+		return err
+	}
+	a.bound = bound
+	return nil
+}
+
+// RemainingCapacity returns how much the tracked plain text bound can still
+// grow through AddChecked before it reaches the PlaintextBound of the public
+// key, i.e. the largest termBound the next AddChecked accepts. It is zero once
+// the accumulator is full and nil if the public key has no plaintext bound.
+func (a *Accumulator) RemainingCapacity() *big.Int {
+	if a.pub.PlaintextBound == nil {
+		return nil
+	}
+
+	// bound - 1 - sum of term bounds
+	remaining := new(big.Int).Sub(a.pub.PlaintextBound, one)
+	remaining.Sub(remaining, a.bound)
+	if remaining.Sign() < 0 {
+		return new(big.Int)
+	}
+	return remaining
+}
+
+// Sum returns the cipher of the running sum.
+func (a *Accumulator) Sum() []byte {
+	return a.sum.Bytes()
+}
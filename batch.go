@@ -0,0 +1,151 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+var ErrNoCiphers = errors.New("okamoto-uchiyama: no ciphers given")
+var ErrInvalidCount = errors.New("okamoto-uchiyama: count and workers must be positive")
+
+// DecryptMulti decrypts each of the passed ciphers to its plain text
+// integer, preserving zero values. The error of a failing cipher is
+// annotated with its index.
+func (priv *PrivateKey) DecryptMulti(ciphers [][]byte) ([]*big.Int, error) {
+	plainTexts := make([]*big.Int, len(ciphers))
+	for i := 0; i < len(ciphers); i++ {
+		m, err := priv.decrypt(ciphers[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: cipher %d", err, i)
+		}
+		plainTexts[i] = m
+	}
+	return plainTexts, nil
+}
+
+// ReRandomizeBatch re-randomizes each of the passed ciphers with an
+// independent random r, e.g. for a periodic refresh of stored ciphers. The
+// input is not modified. The error of a failing cipher is annotated with its
+// index.
+func (pub *PublicKey) ReRandomizeBatch(ciphers [][]byte) ([][]byte, error) {
+	out := make([][]byte, len(ciphers))
+	for i := 0; i < len(ciphers); i++ {
+		c, err := pub.ReRandomize(ciphers[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: cipher %d", err, i)
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+// DecryptMax decrypts all passed ciphers as signed values and returns the
+// largest value and the index of its first occurrence. Max is not a
+// homomorphic operation, so every value is revealed to the key holder.
+func (priv *PrivateKey) DecryptMax(ciphers [][]byte) (*big.Int, int, error) {
+	if len(ciphers) == 0 {
+		return nil, 0, ErrNoCiphers
+	}
+
+	var max *big.Int
+	index := 0
+	for i := 0; i < len(ciphers); i++ {
+		m, err := priv.DecryptSigned(ciphers[i])
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: cipher %d", err, i)
+		}
+		if max == nil || m.Cmp(max) > 0 {
+			max, index = m, i
+		}
+	}
+	return max, index, nil
+}
+
+// lockedReader serializes reads of a shared random source.
+type lockedReader struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (l *lockedReader) Read(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Read(b)
+}
+
+// GenerateKeysParallel generates count independent private keys using a pool
+// of workers goroutines. Reads of random are serialized, so any reader may
+// be passed. It returns the first error encountered, if any.
+func GenerateKeysParallel(random io.Reader, bits, count, workers int) ([]*PrivateKey, error) {
+	if count <= 0 || workers <= 0 {
+		return nil, ErrInvalidCount
+	}
+	random = &lockedReader{r: random}
+
+	keys := make([]*PrivateKey, count)
+	errs := make([]error, count)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				keys[i], errs[i] = GenerateKey(random, bits)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+	}
+	return keys, nil
+}
+
+// DecryptBatchParallel decrypts the passed ciphers like DecryptMulti using a
+// pool of workers goroutines. The result at index i is always the plain text
+// of ciphers[i], however the decryptions complete. It returns the error of
+// the failing cipher with the lowest index, annotated with that index.
+func (priv *PrivateKey) DecryptBatchParallel(ciphers [][]byte, workers int) ([]*big.Int, error) {
+	if workers <= 0 {
+		return nil, ErrInvalidCount
+	}
+
+	plainTexts := make([]*big.Int, len(ciphers))
+	errs := make([]error, len(ciphers))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				plainTexts[i], errs[i] = priv.decrypt(ciphers[i])
+			}
+		}()
+	}
+	for i := 0; i < len(ciphers); i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := 0; i < len(ciphers); i++ {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("%w: cipher %d", errs[i], i)
+		}
+	}
+	return plainTexts, nil
+}
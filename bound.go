@@ -0,0 +1,61 @@
+package okamotoUchiyama
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"io"
+	"math/big"
+)
+
+// boundCommitmentLabel separates the plaintext bound commitment.
+var boundCommitmentLabel = []byte("okamoto-uchiyama plaintext bound")
+
+// boundNonceSize is the byte length of the nonce of the bound commitment.
+const boundNonceSize = 32
+
+// boundCommitment returns SHA-256(label || len(bound) || bound || nonce).
+func boundCommitment(bound *big.Int, nonce []byte) []byte {
+	b := bound.Bytes()
+	h := sha256.New()
+	h.Write(boundCommitmentLabel)
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(b))))
+	h.Write(b)
+	h.Write(nonce)
+	return h.Sum(nil)
+}
+
+// commitPlaintextBound draws a fresh nonce from random and commits the
+// public key to its plaintext bound.
+func (priv *PrivateKey) commitPlaintextBound(random io.Reader) error {
+	nonce := make([]byte, boundNonceSize)
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return err
+	}
+	priv.BoundNonce = nonce
+	priv.PlaintextBoundCommitment = boundCommitment(priv.PlaintextBound, nonce)
+	return nil
+}
+
+// recommitPlaintextBound updates the commitment after the plaintext bound
+// of the key was recomputed. A key without a BoundNonce cannot open a
+// commitment, so its commitment is cleared.
+func (priv *PrivateKey) recommitPlaintextBound() {
+	if priv.BoundNonce == nil || priv.PlaintextBound == nil {
+		priv.PlaintextBoundCommitment = nil
+		return
+	}
+	priv.PlaintextBoundCommitment = boundCommitment(priv.PlaintextBound, priv.BoundNonce)
+}
+
+// VerifyPlaintextBound reports whether bound and nonce open the
+// PlaintextBoundCommitment that GenerateKey published with the key. The key
+// holder reveals its BoundNonce to show that the bound was fixed when the key
+// was made and not changed afterwards. It reports false if the key carries
+// no commitment.
+func (pub *PublicKey) VerifyPlaintextBound(bound *big.Int, nonce []byte) bool {
+	if pub.PlaintextBoundCommitment == nil || bound == nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(boundCommitment(bound, nonce), pub.PlaintextBoundCommitment) == 1
+}
@@ -0,0 +1,97 @@
+package okamotoUchiyama
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+var ErrInvalidCacheSize = errors.New("okamoto-uchiyama: cache size must be positive")
+
+// CachingDecryptor memoizes decryptions of a private key in a fixed-size
+// least-recently-used cache, for workloads that decrypt the same ciphers
+// repeatedly. It is safe for concurrent use. The cache keeps secret plain
+// texts in memory for as long as they stay cached, so the speedup must be
+// weighed against the wider exposure of the plain texts.
+type CachingDecryptor struct {
+	priv *PrivateKey
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // front is the most recently used entry
+	entries map[string]*list.Element
+}
+
+// cacheEntry is a cached plain text keyed by its fixed-width cipher.
+type cacheEntry struct {
+	key       string
+	plainText []byte
+}
+
+// NewCachingDecryptor returns a decryptor caching up to size plain texts.
+func NewCachingDecryptor(priv *PrivateKey, size int) (*CachingDecryptor, error) {
+	if size <= 0 {
+		return nil, ErrInvalidCacheSize
+	}
+	return &CachingDecryptor{
+		priv:    priv,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// Decrypt decrypts the passed cipher text like PrivateKey.Decrypt, returning
+// the cached plain text if the cipher was decrypted before. Stripped and
+// zero-padded ciphers share one entry.
+func (d *CachingDecryptor) Decrypt(cipherText []byte) ([]byte, error) {
+	fixed, err := d.priv.fixedWidth(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	key := string(fixed)
+
+	d.mu.Lock()
+	if e, ok := d.entries[key]; ok {
+		d.order.MoveToFront(e)
+		plainText := e.Value.(*cacheEntry).plainText
+		d.mu.Unlock()
+		return append([]byte{}, plainText...), nil
+	}
+	d.mu.Unlock()
+
+	plainText, err := d.priv.Decrypt(fixed)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.entries[key]; !ok {
+		d.entries[key] = d.order.PushFront(&cacheEntry{
+			key:       key,
+			plainText: append([]byte{}, plainText...),
+		})
+		if d.order.Len() > d.size {
+			oldest := d.order.Back()
+			d.order.Remove(oldest)
+			delete(d.entries, oldest.Value.(*cacheEntry).key)
+		}
+	}
+	return plainText, nil
+}
+
+// Len returns the number of cached plain texts.
+func (d *CachingDecryptor) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len()
+}
+
+// Purge drops all cached plain texts.
+func (d *CachingDecryptor) Purge() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.order.Init()
+	d.entries = make(map[string]*list.Element)
+}
@@ -0,0 +1,86 @@
+package okamotoUchiyama
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidCompact = errors.New("okamoto-uchiyama: invalid compact public key")
+
+// base58Alphabet is the Bitcoin base58 alphabet, which omits 0, O, I and l
+// to avoid misreading.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// compactChecksumSize is the length of the truncated SHA-256 checksum.
+const compactChecksumSize = 4
+
+var base58Radix = big.NewInt(58)
+
+// CompactString encodes the public key as base58 of its DER form followed
+// by a 4-byte SHA-256 checksum, e.g. for a QR code when pairing devices.
+// The alphabet has no punctuation and no easily confused characters. It
+// returns "" if the key cannot be marshaled.
+func (pub *PublicKey) CompactString() string {
+	der, err := MarshalPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return base58Encode(append(der, sum[:compactChecksumSize]...))
+}
+
+// ParseCompactString parses a public key produced by CompactString. It
+// returns ErrInvalidCompact if s has a character outside the base58
+// alphabet or the checksum does not match, e.g. after a misread character.
+func ParseCompactString(s string) (*PublicKey, error) {
+	b, ok := base58Decode(s)
+	if !ok || len(b) <= compactChecksumSize {
+		return nil, ErrInvalidCompact
+	}
+	der, checksum := b[:len(b)-compactChecksumSize], b[len(b)-compactChecksumSize:]
+	sum := sha256.Sum256(der)
+	if !bytes.Equal(checksum, sum[:compactChecksumSize]) {
+		return nil, ErrInvalidCompact
+	}
+	return ParsePublicKey(der)
+}
+
+// base58Encode encodes b in base58, with one '1' per leading zero byte.
+func base58Encode(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, base58Radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58Decode decodes s from base58. It reports false if s contains a
+// character outside the alphabet.
+func base58Decode(s string) ([]byte, bool) {
+	x := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base58Alphabet, s[i])
+		if d < 0 {
+			return nil, false
+		}
+		x.Mul(x, base58Radix)
+		x.Add(x, big.NewInt(int64(d)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), x.Bytes()...), true
+}
@@ -0,0 +1,134 @@
+package okamotoUchiyama
+
+import (
+	"math"
+	"math/big"
+)
+
+// constantTable holds g^k and g^(-k) mod N for k in [0, maxK].
+type constantTable struct {
+	pos []*big.Int
+	neg []*big.Int
+}
+
+// PrecomputeConstants builds a table of g^k and g^(-k) mod N for
+// 0 <= k <= maxK, so that HomomorphicAddConstant and HomomorphicSubConstant
+// with |k| <= maxK become table lookups. It must not be called concurrently
+// with other uses of the public key.
+func (pub *PublicKey) PrecomputeConstants(maxK int) error {
+	if maxK < 0 {
+		return ErrInvalidScalar
+	}
+	ginverse := new(big.Int).ModInverse(pub.G, pub.N)
+	if ginverse == nil {
+		return ErrInvalidKey
+	}
+
+	table := &constantTable{
+		pos: make([]*big.Int, maxK+1),
+		neg: make([]*big.Int, maxK+1),
+	}
+	table.pos[0], table.neg[0] = big.NewInt(1), big.NewInt(1)
+	for k := 1; k <= maxK; k++ {
+		table.pos[k] = new(big.Int).Mod(new(big.Int).Mul(table.pos[k-1], pub.G), pub.N)
+		table.neg[k] = new(big.Int).Mod(new(big.Int).Mul(table.neg[k-1], ginverse), pub.N)
+	}
+	pub.constants = table
+	return nil
+}
+
+// gPow returns g^k mod N for any integer k, using the precomputed table
+// when |k| is small enough. math.MinInt64 is excluded from the lookup, as
+// its negation overflows.
+func (pub *PublicKey) gPow(k *big.Int) (*big.Int, error) {
+	if t := pub.constants; t != nil && k.IsInt64() {
+		if v := k.Int64(); v >= 0 && v < int64(len(t.pos)) {
+			return t.pos[v], nil
+		} else if v < 0 && v > math.MinInt64 && -v < int64(len(t.neg)) {
+			return t.neg[-v], nil
+		}
+	}
+
+	if k.Sign() >= 0 {
+		return new(big.Int).Exp(pub.G, k, pub.N), nil
+	}
+	ginverse := new(big.Int).ModInverse(pub.G, pub.N)
+	if ginverse == nil {
+		return nil, ErrInvalidKey
+	}
+	return new(big.Int).Exp(ginverse, new(big.Int).Neg(k), pub.N), nil
+}
+
+// HomomorphicAddConstant adds the public constant k to the plain text of the
+// passed cipher. The resultant cipher contains m + k.
+func (pub *PublicKey) HomomorphicAddConstant(c []byte, k *big.Int) ([]byte, error) {
+	cipher, err := pub.cipherInt(c)
+	if err != nil {
+		return nil, err
+	}
+	gk, err := pub.gPow(k)
+	if err != nil {
+		return nil, err
+	}
+
+	// C = c * g^k mod N
+	C := new(big.Int).Mod(
+		new(big.Int).Mul(cipher, gk),
+		pub.N,
+	)
+	return C.Bytes(), nil
+}
+
+// HomomorphicSubConstant subtracts the public constant k from the plain text
+// of the passed cipher. The resultant cipher contains m - k mod p.
+func (pub *PublicKey) HomomorphicSubConstant(c []byte, k *big.Int) ([]byte, error) {
+	return pub.HomomorphicAddConstant(c, new(big.Int).Neg(k))
+}
+
+// HomomorphicIncrement adds one to the plain text of the passed cipher by
+// multiplying it with g mod N. The result is linkable to the input cipher,
+// so it should be passed through ReRandomize if unlinkability matters.
+func (pub *PublicKey) HomomorphicIncrement(c []byte) ([]byte, error) {
+	return pub.HomomorphicAddConstant(c, one)
+}
+
+// HomomorphicAffine computes the weighted sum of the passed ciphers with the
+// non-negative public weights plus the non-negative public offset b in one
+// call. The resultant cipher contains w1*m1 + w2*m2 + ... + wn*mn + b. It
+// returns ErrInvalidScalar for a negative weight or offset.
+func (pub *PublicKey) HomomorphicAffine(ciphers [][]byte, weights []*big.Int, offset *big.Int) ([]byte, error) {
+	if offset.Sign() < 0 {
+		return nil, ErrInvalidScalar
+	}
+	C, err := pub.HomomorphicWeightedSum(ciphers, weights)
+	if err != nil {
+		return nil, err
+	}
+	return pub.HomomorphicAddConstant(C, offset)
+}
+
+// HomomorphicConstMinus computes an encryption of k - m from the passed
+// cipher of m and the public constant k, without encrypting k. The resultant
+// cipher g^k * c^(-1) mod N contains k - m mod p. It returns
+// ErrInvalidCiphertext if c is not invertible mod N.
+func (pub *PublicKey) HomomorphicConstMinus(k *big.Int, c []byte) ([]byte, error) {
+	cipher, err := pub.cipherInt(c)
+	if err != nil {
+		return nil, err
+	}
+	cinverse, err := ciphertextInverse(cipher, pub.N)
+	if err != nil {
+		return nil, err
+	}
+	gk, err := pub.gPow(k)
+	if err != nil {
+		return nil, err
+	}
+
+	// C = g^k * c^(-1) mod N
+	C := new(big.Int).Mod(
+		new(big.Int).Mul(gk, cinverse),
+		pub.N,
+	)
+	return C.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+package okamotoUchiyama
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Operation names accepted by OpCostEstimate.
+const (
+	OpEncrypt     = "encrypt"
+	OpDecrypt     = "decrypt"
+	OpAdd         = "add"
+	OpScalarMul   = "scalar-mul"
+	OpReRandomize = "rerandomize"
+)
+
+// calibrationBits is the modulus size of the calibration microbenchmark.
+const calibrationBits = 1024
+
+// Default costs at calibrationBits, kept if calibration cannot run.
+const (
+	defaultExpCost = time.Millisecond
+	defaultMulCost = time.Microsecond
+)
+
+var (
+	calibrateOnce sync.Once
+	expCost       = defaultExpCost // one full-size modular exponentiation
+	mulCost       = defaultMulCost // one modular multiplication
+)
+
+// calibrate measures the cost of a modular exponentiation and a modular
+// multiplication at calibrationBits. If reading the operands from Rand
+// fails, the default costs are kept.
+func calibrate() {
+	const rounds = 8
+	limit := new(big.Int).Lsh(one, calibrationBits)
+	n, err := rand.Int(Rand, limit)
+	if err != nil {
+		return
+	}
+	n.SetBit(n, calibrationBits-1, 1)
+	n.SetBit(n, 0, 1)
+	x, err := rand.Int(Rand, n)
+	if err != nil {
+		return
+	}
+	e, err := rand.Int(Rand, n)
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	z := new(big.Int)
+	for i := 0; i < rounds; i++ {
+		z.Exp(x, e, n)
+	}
+	expCost = time.Since(start) / rounds
+
+	start = time.Now()
+	for i := 0; i < rounds*64; i++ {
+		z.Mod(z.Mul(z, x), n)
+	}
+	mulCost = time.Since(start) / (rounds * 64)
+}
+
+// OpCostEstimate returns a rough estimate of the time one operation takes
+// under the public key. The costs are calibrated by a short microbenchmark at
+// the first call, with rough defaults if Rand fails, and scaled to the size of
+// N, cubically for exponentiations and quadratically for multiplications. It
+// returns 0 for an unknown op.
+func (pub *PublicKey) OpCostEstimate(op string) time.Duration {
+	calibrateOnce.Do(calibrate)
+
+	scale := float64(pub.N.BitLen()) / calibrationBits
+	exp := float64(expCost) * scale * scale * scale
+	mul := float64(mulCost) * scale * scale
+
+	var cost float64
+	switch op {
+	case OpEncrypt:
+		// g^m * h^r mod N
+		cost = 2*exp + mul
+	case OpDecrypt:
+		// c^(p-1) mod p^2, where p^2 has 2/3 and p has 1/3 of the bits of N
+		cost = exp * 4 / 27
+	case OpAdd:
+		// c1 * c2 mod N
+		cost = mul
+	case OpScalarMul:
+		// c^k mod N
+		cost = exp
+	case OpReRandomize:
+		// c * h^r mod N
+		cost = exp + mul
+	default:
+		return 0
+	}
+
+	if cost < 1 {
+		return 1
+	}
+	return time.Duration(cost)
+}
+
+// ExpectedPrimeAttempts estimates how many candidates rand.Prime tests on
+// average to find a prime of the given bit length. By the prime number
+// theorem a random integer near 2^bits is prime with probability about
+// 1/(bits*ln 2), and only odd candidates are tried, which doubles the odds.
+// This gives no bound on a single run, but helps budgeting timeouts.
+func ExpectedPrimeAttempts(bits int) float64 {
+	if bits < 2 {
+		return 1
+	}
+	return math.Max(1, float64(bits)*math.Ln2/2)
+}
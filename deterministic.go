@@ -0,0 +1,145 @@
+package okamotoUchiyama
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+var ErrInvalidCompressed = errors.New("okamoto-uchiyama: invalid compressed cipher encoding")
+var ErrSeedMismatch = errors.New("okamoto-uchiyama: cipher was not encrypted with the seed")
+
+// deterministicLabel separates the key of EncryptDeterministic.
+var deterministicLabel = []byte("okamoto-uchiyama deterministic")
+
+// deriveR derives r in {1...n-1} from key and the length-prefixed parts with
+// HMAC-SHA256 in counter mode. It draws 128 bits more than N has, so the
+// reduction mod N-1 is statistically uniform.
+func (pub *PublicKey) deriveR(key []byte, parts ...[]byte) *big.Int {
+	size := pub.ciphertextSize() + 16
+	stream := make([]byte, 0, size+sha256.Size)
+	for counter := uint32(0); len(stream) < size; counter++ {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(binary.BigEndian.AppendUint32(nil, counter))
+		for _, part := range parts {
+			mac.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+			mac.Write(part)
+		}
+		stream = mac.Sum(stream)
+	}
+
+	// r = x mod (N-1) + 1
+	r := new(big.Int).SetBytes(stream[:size])
+	r.Mod(r, new(big.Int).Sub(pub.N, one))
+	return r.Add(r, one)
+}
+
+// EncryptDeterministic encrypts a plain text with r derived from an HMAC of
+// the plain text and context under a key derived from the public key, so
+// the same key, plain text and context always give the same cipher. This
+// gives up semantic security: equal plain texts under equal contexts are
+// visible as equal ciphers, and since the HMAC key follows from the public
+// key anyone can test a guessed plain text. Use it only where a protocol needs
+// such repeatable ciphers.
+func (pub *PublicKey) EncryptDeterministic(plainText, context []byte) ([]byte, error) {
+	m := new(big.Int).SetBytes(plainText)
+	if !pub.PlaintextFits(m) {
+		return nil, ErrLargeMessage
+	}
+
+	fingerprint, err := pub.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, deterministicLabel)
+	mac.Write(fingerprint)
+	key := mac.Sum(nil)
+
+	r := pub.deriveR(key, plainText, context)
+	return pub.encrypt(m, r).Bytes(), nil
+}
+
+// seedLabel separates the key of EncryptSeeded.
+var seedLabel = []byte("okamoto-uchiyama seeded")
+
+// EncryptSeeded encrypts a plain text with r derived from the caller's secret
+// seed by HMAC-SHA256, so the cipher can be rebuilt later from the plain text
+// and seed alone, see CompressCiphertext. The seed must be random, at least
+// 16 bytes, and never reused for another plain text.
+func (pub *PublicKey) EncryptSeeded(plainText, seed []byte) ([]byte, error) {
+	m := new(big.Int).SetBytes(plainText)
+	if !pub.PlaintextFits(m) {
+		return nil, ErrLargeMessage
+	}
+	r := pub.deriveR(seedLabel, seed)
+	return pub.encrypt(m, r).Bytes(), nil
+}
+
+// CompressCiphertext encodes a cipher produced by EncryptSeeded as the seed
+// followed by the plain text, which takes far fewer bytes than the cipher,
+// whose size is dominated by h^r. The compressed form holds the plain text in
+// the clear and the seed, so it is only meant for storage by the encrypting
+// party, and DecompressCiphertext needs the same public key to expand it. It
+// returns ErrSeedMismatch if c is not the seeded cipher of the plain text.
+func (pub *PublicKey) CompressCiphertext(c, plainText, seed []byte) ([]byte, error) {
+	cipher, err := pub.cipherInt(c)
+	if err != nil {
+		return nil, err
+	}
+	seeded, err := pub.EncryptSeeded(plainText, seed)
+	if err != nil {
+		return nil, err
+	}
+	if cipher.Cmp(new(big.Int).SetBytes(seeded)) != 0 {
+		return nil, ErrSeedMismatch
+	}
+	return append(lengthPrefix(seed), plainText...), nil
+}
+
+// DecompressCiphertext rebuilds the cipher from the output of
+// CompressCiphertext under the same public key.
+func (pub *PublicKey) DecompressCiphertext(compressed []byte) ([]byte, error) {
+	n, size := binary.Uvarint(compressed)
+	if size <= 0 || n > uint64(len(compressed)-size) {
+		return nil, ErrInvalidCompressed
+	}
+	rest := bytes.NewBuffer(compressed[size:])
+	seed := rest.Next(int(n))
+	return pub.EncryptSeeded(rest.Bytes(), seed)
+}
+
+// labelledKeyLabel separates the exponent of DeriveLabelledPublicKey.
+var labelledKeyLabel = []byte("okamoto-uchiyama labelled key")
+
+// DeriveLabelledPublicKey deterministically derives a public key for the
+// label that shares N, and thus the factorization, with priv. Its generator is
+// G' = G * H^s mod N with s derived from the label by HMAC-SHA256. Since
+// H^(p-1) = 1 mod p^2, G' has the same GD as G, so ciphers under every
+// labelled key decrypt with priv unchanged, and distinct labels give distinct
+// generators. Only public values enter the derivation, so the labels
+// separate ciphers by convention, not cryptographically.
+func (priv *PrivateKey) DeriveLabelledPublicKey(label []byte) (*PublicKey, error) {
+	s := priv.deriveR(labelledKeyLabel, label)
+
+	// g' = g * h^s mod n
+	g := new(big.Int).Mod(
+		new(big.Int).Mul(priv.G, new(big.Int).Exp(priv.H, s, priv.N)),
+		priv.N,
+	)
+
+	// h' = g'^n mod n
+	h := new(big.Int).Exp(g, priv.N, priv.N)
+	if !acceptGenerator(new(big.Int).Exp(g, new(big.Int).Sub(priv.P, one), priv.PSquared), h, priv.P) {
+		return nil, ErrInvalidKey
+	}
+	return &PublicKey{
+		N: new(big.Int).Set(priv.N),
+		G: g,
+		H: h,
+
+		PlaintextBound: cloneInt(priv.PlaintextBound),
+	}, nil
+}
@@ -0,0 +1,465 @@
+package okamotoUchiyama
+
+import (
+	"encoding"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("okamoto-uchiyama: invalid key encoding")
+var ErrInvalidPEM = errors.New("okamoto-uchiyama: invalid PEM block")
+var ErrKeyPairMismatch = errors.New("okamoto-uchiyama: private and public key do not match")
+var ErrEnvNotSet = errors.New("okamoto-uchiyama: environment variable is not set")
+
+// PEM block types of the encoded keys.
+const (
+	PrivateKeyPEMType = "OKAMOTO-UCHIYAMA PRIVATE KEY"
+	PublicKeyPEMType  = "OKAMOTO-UCHIYAMA PUBLIC KEY"
+)
+
+// publicKeyASN1 is the ASN.1 DER layout of a public key.
+type publicKeyASN1 struct {
+	N              *big.Int
+	G              *big.Int
+	H              *big.Int
+	PlaintextBound *big.Int `asn1:"optional"`
+
+	PlaintextBoundCommitment []byte `asn1:"optional,explicit,tag:0"`
+}
+
+// privateKeyVersion is the version of the private key layout.
+const privateKeyVersion = 1
+
+// privateKeyASN1 is the ASN.1 DER layout of a private key. GD and PSquared
+// are derived from P and the public key, so they are tagged and may be
+// left out by other serializers. BoundNonce is only present for keys with a
+// plaintext bound commitment, which is derived from it again.
+type privateKeyASN1 struct {
+	Version  int
+	N        *big.Int
+	G        *big.Int
+	H        *big.Int
+	P        *big.Int
+	GD       *big.Int `asn1:"optional,explicit,tag:0"`
+	PSquared *big.Int `asn1:"optional,explicit,tag:1"`
+
+	BoundNonce []byte `asn1:"optional,explicit,tag:2"`
+}
+
+// MarshalPublicKey converts a public key to ASN.1 DER form.
+func MarshalPublicKey(pub *PublicKey) ([]byte, error) {
+	return asn1.Marshal(publicKeyASN1{
+		N:              pub.N,
+		G:              pub.G,
+		H:              pub.H,
+		PlaintextBound: pub.PlaintextBound,
+
+		PlaintextBoundCommitment: pub.PlaintextBoundCommitment,
+	})
+}
+
+// ParsePublicKey parses a public key in ASN.1 DER form.
+func ParsePublicKey(der []byte) (*PublicKey, error) {
+	var k publicKeyASN1
+	rest, err := asn1.Unmarshal(der, &k)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrInvalidKey
+	}
+	if !positive(k.N, k.G, k.H) {
+		return nil, ErrInvalidKey
+	}
+	if k.PlaintextBound != nil && !positive(k.PlaintextBound) {
+		return nil, ErrInvalidKey
+	}
+	return &PublicKey{
+		N: k.N,
+		G: k.G,
+		H: k.H,
+
+		PlaintextBound: k.PlaintextBound,
+
+		PlaintextBoundCommitment: k.PlaintextBoundCommitment,
+	}, nil
+}
+
+var (
+	_ encoding.TextMarshaler   = (*PublicKey)(nil)
+	_ encoding.TextUnmarshaler = (*PublicKey)(nil)
+	_ encoding.TextMarshaler   = (*PrivateKey)(nil)
+	_ encoding.TextUnmarshaler = (*PrivateKey)(nil)
+)
+
+// MarshalText encodes the public key as "N:<hex>,G:<hex>,H:<hex>", followed
+// by ",B:<hex>" when PlaintextBound is set and ",C:<hex>" when
+// PlaintextBoundCommitment is set. It implements encoding.TextMarshaler.
+func (pub *PublicKey) MarshalText() ([]byte, error) {
+	if !positive(pub.N, pub.G, pub.H) {
+		return nil, ErrInvalidKey
+	}
+
+	text := "N:" + pub.N.Text(16) + ",G:" + pub.G.Text(16) + ",H:" + pub.H.Text(16)
+	if pub.PlaintextBound != nil {
+		text += ",B:" + pub.PlaintextBound.Text(16)
+	}
+	if pub.PlaintextBoundCommitment != nil {
+		text += ",C:" + hex.EncodeToString(pub.PlaintextBoundCommitment)
+	}
+	return []byte(text), nil
+}
+
+// UnmarshalText parses a public key produced by MarshalText. It implements
+// encoding.TextUnmarshaler and returns ErrInvalidKey if any of N, G and H is
+// missing, or a field is repeated, unknown or not valid hex. Fields that are
+// absent from text, such as C, are reset.
+func (pub *PublicKey) UnmarshalText(text []byte) error {
+	fields := make(map[string]*big.Int)
+	var commitment []byte
+	for _, field := range strings.Split(string(text), ",") {
+		name, value, ok := strings.Cut(field, ":")
+		if !ok || fields[name] != nil {
+			return ErrInvalidKey
+		}
+		// the commitment is a hash, not an integer, so keep leading zeros
+		if name == "C" {
+			c, err := hex.DecodeString(value)
+			if err != nil || len(c) == 0 || commitment != nil {
+				return ErrInvalidKey
+			}
+			commitment = c
+			continue
+		}
+		switch name {
+		case "N", "G", "H", "B":
+		default:
+			return ErrInvalidKey
+		}
+
+		x, ok := new(big.Int).SetString(value, 16)
+		if !ok || x.Sign() <= 0 {
+			return ErrInvalidKey
+		}
+		fields[name] = x
+	}
+	if !positive(fields["N"], fields["G"], fields["H"]) {
+		return ErrInvalidKey
+	}
+
+	// replace the whole key, so no value derived from the old one survives
+	*pub = PublicKey{
+		N: fields["N"],
+		G: fields["G"],
+		H: fields["H"],
+
+		PlaintextBound: fields["B"],
+
+		PlaintextBoundCommitment: commitment,
+	}
+	return nil
+}
+
+// MarshalText encodes the private key as base64 of its ASN.1 DER form. It
+// implements encoding.TextMarshaler, and keeps a PrivateKey from promoting
+// the method of its PublicKey, which would silently drop the private values
+// e.g. in encoding/json.
+func (priv *PrivateKey) MarshalText() ([]byte, error) {
+	der, err := MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	text := make([]byte, base64.StdEncoding.EncodedLen(len(der)))
+	base64.StdEncoding.Encode(text, der)
+	return text, nil
+}
+
+// UnmarshalText parses a private key produced by MarshalText like
+// ParsePrivateKey. It implements encoding.TextUnmarshaler and returns
+// ErrInvalidKey if text is not valid base64.
+func (priv *PrivateKey) UnmarshalText(text []byte) error {
+	der := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(der, text)
+	if err != nil {
+		return ErrInvalidKey
+	}
+	k, err := ParsePrivateKey(der[:n])
+	if err != nil {
+		return err
+	}
+	*priv = *k
+	return nil
+}
+
+// MarshalPrivateKey converts a private key to ASN.1 DER form.
+func MarshalPrivateKey(priv *PrivateKey) ([]byte, error) {
+	return asn1.Marshal(privateKeyASN1{
+		Version:  privateKeyVersion,
+		N:        priv.N,
+		G:        priv.G,
+		H:        priv.H,
+		P:        priv.P,
+		GD:       priv.GD,
+		PSquared: priv.PSquared,
+
+		BoundNonce: priv.BoundNonce,
+	})
+}
+
+// ParsePrivateKey parses a private key in ASN.1 DER form. The plaintext
+// bound is not stored and is derived again from P, as are GD and PSquared
+// when absent. A present PSquared is kept as is and checked by Validate.
+func ParsePrivateKey(der []byte) (*PrivateKey, error) {
+	var k privateKeyASN1
+	rest, err := asn1.Unmarshal(der, &k)
+	if err != nil || len(rest) != 0 || k.Version != privateKeyVersion {
+		return nil, ErrInvalidKey
+	}
+	if !positive(k.N, k.G, k.H, k.P) {
+		return nil, ErrInvalidKey
+	}
+	if (k.GD != nil && !positive(k.GD)) || (k.PSquared != nil && !positive(k.PSquared)) {
+		return nil, ErrInvalidKey
+	}
+
+	priv := &PrivateKey{
+		PublicKey: PublicKey{
+			N: k.N,
+			G: k.G,
+			H: k.H,
+
+			PlaintextBound: plaintextBound(k.P),
+		},
+		GD:       k.GD,
+		P:        k.P,
+		PSquared: k.PSquared,
+	}
+	if priv.PSquared == nil {
+		priv.PSquared = new(big.Int).Mul(k.P, k.P)
+	}
+	if priv.GD == nil {
+		priv.GD = priv.ComputeGD()
+	}
+	if k.BoundNonce != nil {
+		priv.BoundNonce = k.BoundNonce
+		priv.PlaintextBoundCommitment = boundCommitment(priv.PlaintextBound, k.BoundNonce)
+	}
+	return priv, nil
+}
+
+// privateKeyASN1V0 is the layout of version 0 private keys, which stored
+// all fields untagged with GD before P. It was only used during development
+// before the tagged version 1 layout and never appeared in a release.
+type privateKeyASN1V0 struct {
+	Version  int
+	N        *big.Int
+	G        *big.Int
+	H        *big.Int
+	GD       *big.Int
+	P        *big.Int
+	PSquared *big.Int
+}
+
+// ParsePrivateKeyCompat parses a private key in ASN.1 DER form like
+// ParsePrivateKey, and falls back to the unreleased version 0 layout, e.g.
+// for keys written by development builds. Since a key in one layout may
+// happen to decode in another, each candidate must also pass Validate. It
+// returns ErrInvalidKey if no layout yields a valid key.
+func ParsePrivateKeyCompat(der []byte) (*PrivateKey, error) {
+	if priv, err := ParsePrivateKey(der); err == nil && priv.Validate() == nil {
+		return priv, nil
+	}
+
+	var k privateKeyASN1V0
+	rest, err := asn1.Unmarshal(der, &k)
+	if err != nil || len(rest) != 0 || k.Version != 0 {
+		return nil, ErrInvalidKey
+	}
+	if !positive(k.N, k.G, k.H, k.GD, k.P, k.PSquared) {
+		return nil, ErrInvalidKey
+	}
+	priv := &PrivateKey{
+		PublicKey: PublicKey{
+			N: k.N,
+			G: k.G,
+			H: k.H,
+
+			PlaintextBound: plaintextBound(k.P),
+		},
+		GD:       k.GD,
+		P:        k.P,
+		PSquared: k.PSquared,
+	}
+	if priv.Validate() != nil {
+		return nil, ErrInvalidKey
+	}
+	return priv, nil
+}
+
+// EncodePublicKeyPEM encodes a public key as a PEM block.
+func EncodePublicKeyPEM(pub *PublicKey) ([]byte, error) {
+	der, err := MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: der}), nil
+}
+
+// DecodePublicKeyPEM decodes a public key from the first PEM block of data.
+func DecodePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PublicKeyPEMType {
+		return nil, ErrInvalidPEM
+	}
+	return ParsePublicKey(block.Bytes)
+}
+
+// EncodePrivateKeyPEM encodes a private key as a PEM block.
+func EncodePrivateKeyPEM(priv *PrivateKey) ([]byte, error) {
+	der, err := MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PrivateKeyPEMType, Bytes: der}), nil
+}
+
+// DecodePrivateKeyPEM decodes a private key from the first PEM block of data.
+func DecodePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PrivateKeyPEMType {
+		return nil, ErrInvalidPEM
+	}
+	return ParsePrivateKey(block.Bytes)
+}
+
+// EncodeKeyPairPEM encodes the private key and its public key as two
+// consecutive PEM blocks, private first.
+func EncodeKeyPairPEM(priv *PrivateKey) ([]byte, error) {
+	privPEM, err := EncodePrivateKeyPEM(priv)
+	if err != nil {
+		return nil, err
+	}
+	pubPEM, err := EncodePublicKeyPEM(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(privPEM, pubPEM...), nil
+}
+
+// DecodeKeyPairPEM decodes a private and a public key PEM block from data,
+// in any order. It returns ErrInvalidPEM unless exactly one block of each
+// type is present, and ErrKeyPairMismatch if the keys do not belong together.
+func DecodeKeyPairPEM(data []byte) (*PrivateKey, *PublicKey, error) {
+	var priv *PrivateKey
+	var pub *PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var err error
+		switch {
+		case block.Type == PrivateKeyPEMType && priv == nil:
+			priv, err = ParsePrivateKey(block.Bytes)
+		case block.Type == PublicKeyPEMType && pub == nil:
+			pub, err = ParsePublicKey(block.Bytes)
+		default:
+			return nil, nil, ErrInvalidPEM
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if priv == nil || pub == nil {
+		return nil, nil, ErrInvalidPEM
+	}
+
+	if !priv.MatchesPublic(pub) {
+		return nil, nil, ErrKeyPairMismatch
+	}
+	return priv, pub, nil
+}
+
+// MatchesPublic reports whether pub is the public key of priv, i.e. N, G and
+// H are equal. The PlaintextBound is not compared, since it may be unset.
+func (priv *PrivateKey) MatchesPublic(pub *PublicKey) bool {
+	return priv.N.Cmp(pub.N) == 0 && priv.G.Cmp(pub.G) == 0 && priv.H.Cmp(pub.H) == 0
+}
+
+// EncryptBase64 encrypts a plain text like Encrypt and returns the
+// fixed-width cipher in standard base64 encoding.
+func (pub *PublicKey) EncryptBase64(plainText []byte) (string, error) {
+	cipher, err := pub.Encrypt(plainText)
+	if err != nil {
+		return "", err
+	}
+	fixed, err := pub.fixedWidth(cipher)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(fixed), nil
+}
+
+// DecryptBase64 decrypts a standard base64 encoded cipher produced by
+// EncryptBase64. It returns an error if s is not valid base64.
+func (priv *PrivateKey) DecryptBase64(s string) ([]byte, error) {
+	cipher, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return priv.Decrypt(cipher)
+}
+
+// LoadPrivateKeyFromEnv reads a PEM encoded private key from the named
+// environment variable. It returns an error wrapping ErrEnvNotSet if the
+// variable is unset or empty, and a parse error if the value is malformed.
+func LoadPrivateKeyFromEnv(name string) (*PrivateKey, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%w: %s", ErrEnvNotSet, name)
+	}
+
+	priv, err := DecodePrivateKeyPEM([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, name)
+	}
+	return priv, nil
+}
+
+// positive reports whether all passed integers are non-nil and greater than zero.
+func positive(xs ...*big.Int) bool {
+	for _, x := range xs {
+		if x == nil || x.Sign() <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Dump returns a labeled multi-line text dump of the key parameters in the
+// spirit of "openssl rsa -text": each of N, P, G and H in hex, annotated with
+// its bit length. The dump contains the secret prime P and must be handled
+// like the private key itself.
+func (priv *PrivateKey) Dump() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Okamoto-Uchiyama Private-Key: (%d bit)\n", priv.N.BitLen())
+	b.WriteString("WARNING: contains secret key material\n")
+	for _, field := range []struct {
+		label string
+		x     *big.Int
+	}{
+		{"modulus (N)", priv.N},
+		{"prime (P)", priv.P},
+		{"generator (G)", priv.G},
+		{"public element (H)", priv.H},
+	} {
+		fmt.Fprintf(&b, "%s: (%d bit)\n    %s\n", field.label, field.x.BitLen(), field.x.Text(16))
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrFixedPointPrecision = errors.New("okamoto-uchiyama: value has more decimal places than the scale")
+
+// fixedPointFactor returns 10^scale, or ErrInvalidScalar for a negative scale.
+func fixedPointFactor(scale int) (*big.Int, error) {
+	if scale < 0 {
+		return nil, ErrInvalidScalar
+	}
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil), nil
+}
+
+// EncryptFixedPoint encrypts the decimal value as the integer value * 10^scale.
+// Ciphers of the same scale add homomorphically, and DecryptFixedPoint with
+// that scale recovers the sum. Negative values are encrypted mod N like
+// EncryptMod. It returns ErrFixedPointPrecision if value has more than scale
+// decimal places, ErrNoPlaintextBound if the bound is unset, and
+// ErrLargeMessage if the magnitude of the scaled value is not below half the
+// PlaintextBound, beyond which DecryptFixedPoint would wrap it around.
+func (pub *PublicKey) EncryptFixedPoint(value *big.Rat, scale int) ([]byte, error) {
+	factor, err := fixedPointFactor(scale)
+	if err != nil {
+		return nil, err
+	}
+
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(factor))
+	if !scaled.IsInt() {
+		return nil, ErrFixedPointPrecision
+	}
+	m := scaled.Num()
+	if err := pub.checkSignedPlaintext(m); err != nil {
+		return nil, err
+	}
+	return pub.EncryptMod(m)
+}
+
+// DecryptFixedPoint decrypts a cipher produced by EncryptFixedPoint, or a
+// homomorphic sum of such ciphers, with the same scale. The plain text is
+// read as a signed integer like DecryptSigned and divided by 10^scale.
+func (priv *PrivateKey) DecryptFixedPoint(c []byte, scale int) (*big.Rat, error) {
+	factor, err := fixedPointFactor(scale)
+	if err != nil {
+		return nil, err
+	}
+	m, err := priv.DecryptSigned(c)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetFrac(m, factor), nil
+}
@@ -0,0 +1,34 @@
+package okamotoUchiyama
+
+import "math/big"
+
+// GenericCiphertext is a scheme-independent view of a cipher for generic
+// homomorphic pipelines: the cipher as an integer and the modulus of the
+// group it lives in. For this scheme the homomorphic addition is the
+// product of Values mod Modulus.
+type GenericCiphertext struct {
+	Value   *big.Int
+	Modulus *big.Int
+}
+
+// ToGeneric converts the passed cipher to a GenericCiphertext under the
+// public key's modulus N.
+func (pub *PublicKey) ToGeneric(c []byte) GenericCiphertext {
+	return GenericCiphertext{
+		Value:   new(big.Int).SetBytes(c),
+		Modulus: new(big.Int).Set(pub.N),
+	}
+}
+
+// FromGeneric converts a GenericCiphertext back to a cipher. It returns nil
+// if the generic cipher does not belong to the public key, i.e. its modulus
+// is not N or its value is not below N.
+func (pub *PublicKey) FromGeneric(g GenericCiphertext) []byte {
+	if g.Value == nil || g.Modulus == nil || g.Modulus.Cmp(pub.N) != 0 {
+		return nil
+	}
+	if g.Value.Sign() < 0 || g.Value.Cmp(pub.N) >= 0 {
+		return nil
+	}
+	return g.Value.Bytes()
+}
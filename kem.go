@@ -0,0 +1,75 @@
+package okamotoUchiyama
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+)
+
+// kemLabel separates the shared key derivation from other SHA-256 uses.
+var kemLabel = []byte("okamoto-uchiyama kem")
+
+// kemSecretBits returns the size of the random secret element used by
+// Encapsulate. The PlaintextBound is 2^(bitlen(p)-1), so a value of
+// bitlen(p)-1 bits always stays below it, whatever the size of q. It returns
+// ErrNoPlaintextBound if the bound is unset.
+func (pub *PublicKey) kemSecretBits() (int, error) {
+	if pub.PlaintextBound == nil {
+		return 0, ErrNoPlaintextBound
+	}
+	return pub.PlaintextBound.BitLen() - 1, nil
+}
+
+// kdf derives the shared key as SHA-256(kemLabel || s), where s is the
+// secret element left-padded to the fixed byte width of kemSecretBits.
+func (pub *PublicKey) kdf(secret *big.Int) ([]byte, error) {
+	bits, err := pub.kemSecretBits()
+	if err != nil {
+		return nil, err
+	}
+	if secret.BitLen() > bits {
+		return nil, ErrLargeMessage
+	}
+	s := make([]byte, (bits+7)/8)
+	secret.FillBytes(s)
+
+	h := sha256.New()
+	h.Write(kemLabel)
+	h.Write(s)
+	return h.Sum(nil), nil
+}
+
+// Encapsulate generates a random secret element of the plaintext space,
+// encrypts it and derives a 32-byte shared key from it. The key is
+// SHA-256 over a fixed label and the fixed-width secret, see Decapsulate.
+// It returns ErrNoPlaintextBound if the bound of the key is unset.
+func (pub *PublicKey) Encapsulate() (cipherText []byte, sharedKey []byte, err error) {
+	bits, err := pub.kemSecretBits()
+	if err != nil {
+		return nil, nil, err
+	}
+	secret, err := rand.Int(Rand, new(big.Int).Lsh(one, uint(bits)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cipherText, err = pub.Encrypt(secret.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedKey, err = pub.kdf(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cipherText, sharedKey, nil
+}
+
+// Decapsulate decrypts the passed cipher text produced by Encapsulate and
+// derives the same 32-byte shared key from the recovered secret element.
+func (priv *PrivateKey) Decapsulate(cipherText []byte) (sharedKey []byte, err error) {
+	m, err := priv.Decrypt(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	return priv.kdf(new(big.Int).SetBytes(m))
+}
@@ -0,0 +1,49 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrKeyNotFound = errors.New("okamoto-uchiyama: no key with this name in the keystore")
+
+// Keystore holds private keys under names in memory. It is safe for
+// concurrent use. The keys themselves are shared, not copied, so they must
+// not be modified while stored.
+type Keystore struct {
+	mu   sync.RWMutex
+	keys map[string]*PrivateKey
+}
+
+// NewKeystore returns an empty keystore.
+func NewKeystore() *Keystore {
+	return &Keystore{keys: make(map[string]*PrivateKey)}
+}
+
+// Add stores priv under name, replacing any key stored under it before.
+func (ks *Keystore) Add(name string, priv *PrivateKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys == nil {
+		ks.keys = make(map[string]*PrivateKey)
+	}
+	ks.keys[name] = priv
+}
+
+// Get returns the key stored under name and whether there is one.
+func (ks *Keystore) Get(name string) (*PrivateKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	priv, ok := ks.keys[name]
+	return priv, ok
+}
+
+// EncryptWith encrypts a plain text like Encrypt under the public key of the
+// key stored under name. It returns ErrKeyNotFound if there is none.
+func (ks *Keystore) EncryptWith(name string, plain []byte) ([]byte, error) {
+	priv, ok := ks.Get(name)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return priv.PublicKey.Encrypt(plain)
+}
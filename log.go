@@ -0,0 +1,19 @@
+package okamotoUchiyama
+
+// Logger receives debug traces of key generation, encryption and
+// decryption. The messages never contain secret values, only sizes and
+// progress.
+type Logger interface {
+	Debugf(format string, args ...any)
+}
+
+// Log is the Logger of all operations. It is nil by default, which disables
+// the traces. Like Rand, it must only be replaced while no operation runs.
+var Log Logger
+
+// debugf forwards a trace to Log if it is set.
+func debugf(format string, args ...any) {
+	if Log != nil {
+		Log.Debugf(format, args...)
+	}
+}
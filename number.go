@@ -0,0 +1,67 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrNumberRange = errors.New("okamoto-uchiyama: plain text does not fit in the integer type")
+
+// Integer is satisfied by all built-in signed and unsigned integer types.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// isSigned reports whether T is a signed integer type.
+func isSigned[T Integer]() bool {
+	var zero T
+	return zero-1 < zero
+}
+
+// EncryptNumber encrypts the Go integer v. Negative values are encrypted mod
+// N like EncryptMod and decode again with DecryptNumber of the same type.
+// Values of signed types must be below half the PlaintextBound in magnitude,
+// as DecryptNumber reads them like DecryptSigned, and unsigned values below
+// the bound itself. It returns ErrLargeMessage otherwise.
+func EncryptNumber[T Integer](pub *PublicKey, v T) ([]byte, error) {
+	if isSigned[T]() {
+		m := big.NewInt(int64(v))
+		if err := pub.checkSignedPlaintext(m); err != nil {
+			return nil, err
+		}
+		return pub.EncryptMod(m)
+	}
+
+	m := new(big.Int).SetUint64(uint64(v))
+	if !pub.PlaintextFits(m) {
+		return nil, ErrLargeMessage
+	}
+	return pub.EncryptMod(m)
+}
+
+// DecryptNumber decrypts a cipher produced by EncryptNumber, or a
+// homomorphic combination of such ciphers, to the integer type T. Signed
+// types read the plain text like DecryptSigned. It returns ErrNumberRange if
+// the value does not fit in T.
+func DecryptNumber[T Integer](priv *PrivateKey, c []byte) (T, error) {
+	if isSigned[T]() {
+		m, err := priv.DecryptSigned(c)
+		if err != nil {
+			return 0, err
+		}
+		if !m.IsInt64() || int64(T(m.Int64())) != m.Int64() {
+			return 0, ErrNumberRange
+		}
+		return T(m.Int64()), nil
+	}
+
+	m, err := priv.decrypt(c)
+	if err != nil {
+		return 0, err
+	}
+	if !m.IsUint64() || uint64(T(m.Uint64())) != m.Uint64() {
+		return 0, ErrNumberRange
+	}
+	return T(m.Uint64()), nil
+}
@@ -2,14 +2,31 @@ package okamotoUchiyama
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
 	"io"
 	"math/big"
 )
 
 var one = big.NewInt(1)
-var ErrLargeMessage = errors.New("okamoto-uchiyama: message is larger than Schmidt Samoa public key size")
-var ErrLargeCipher = errors.New("okamoto-uchiyama: message is larger than Schmidt Samoa public key size")
+
+// Rand is the source of randomness of all operations that do not take an
+// explicit io.Reader, such as Encrypt. It defaults to crypto/rand.Reader and
+// may be replaced, e.g. by a deterministic reader in tests. It is read
+// without synchronization, so it must only be replaced while no operation
+// runs, and the reader itself must be safe for concurrent use if the
+// operations are.
+var Rand io.Reader = rand.Reader
+
+var ErrLargeMessage = errors.New("okamoto-uchiyama: message is larger than Okamoto-Uchiyama public key size")
+var ErrLargeCipher = errors.New("okamoto-uchiyama: cipher is larger than Okamoto-Uchiyama public key size")
+var ErrInvalidRandom = errors.New("okamoto-uchiyama: random value r is not in [1, N-1]")
+var ErrInvalidScalar = errors.New("okamoto-uchiyama: scalar is out of range")
+var ErrLengthMismatch = errors.New("okamoto-uchiyama: number of ciphers and weights differ")
+var ErrImplausibleCiphertext = errors.New("okamoto-uchiyama: cipher is implausibly small for modulus N")
+var ErrPlaintextSize = errors.New("okamoto-uchiyama: plain text does not fit in the requested size")
+var ErrInvalidCiphertext = errors.New("okamoto-uchiyama: cipher is not an element of the multiplicative group mod N")
 
 // PrivateKey represents a Okamoto-Uchiyama private key.
 type PrivateKey struct {
@@ -17,139 +34,826 @@ type PrivateKey struct {
 	GD       *big.Int
 	P        *big.Int
 	PSquared *big.Int
+
+	// BoundNonce opens the PlaintextBoundCommitment of the public key, see
+	// VerifyPlaintextBound. It is nil if there is no commitment.
+	BoundNonce []byte
+
+	// precomputed holds the values cached by Precompute.
+	precomputed *precomputedValues
 }
 
-// PublicKey represents Okamoto-Uchiyama public key.
+// PublicKey represents Okamoto-Uchiyama public key. Encryption and the
+// homomorphic operations only read the key, so they are safe for concurrent
+// use as long as the key is not modified meanwhile.
 type PublicKey struct {
 	N *big.Int
 	G *big.Int
 	H *big.Int
+
+	// PlaintextBound is a power of two strictly below the secret prime p.
+	// Messages must be smaller than it to decrypt correctly. It only reveals
+	// the bit length of p, which already follows from the size of N. It is
+	// nil for keys that were built without it, in which case only the
+	// modulus N bounds the messages.
+	PlaintextBound *big.Int
+
+	// PlaintextBoundCommitment commits to PlaintextBound at key generation,
+	// see VerifyPlaintextBound. It is nil for keys built without it.
+	PlaintextBoundCommitment []byte
+
+	// constants is the table built by PrecomputeConstants.
+	constants *constantTable
+}
+
+// Clone returns a deep copy of the public key.
+func (pub *PublicKey) Clone() *PublicKey {
+	return &PublicKey{
+		N: cloneInt(pub.N),
+		G: cloneInt(pub.G),
+		H: cloneInt(pub.H),
+
+		PlaintextBound: cloneInt(pub.PlaintextBound),
+
+		PlaintextBoundCommitment: cloneBytes(pub.PlaintextBoundCommitment),
+	}
+}
+
+// Clone returns a deep copy of the private key, including its public key.
+func (priv *PrivateKey) Clone() *PrivateKey {
+	return &PrivateKey{
+		PublicKey: *priv.PublicKey.Clone(),
+		GD:        cloneInt(priv.GD),
+		P:         cloneInt(priv.P),
+		PSquared:  cloneInt(priv.PSquared),
+
+		BoundNonce: cloneBytes(priv.BoundNonce),
+	}
+}
+
+// plaintextBound returns the largest power of two strictly below the prime p.
+func plaintextBound(p *big.Int) *big.Int {
+	return new(big.Int).Lsh(one, uint(p.BitLen()-1))
+}
+
+// randomR returns a uniformly random r from {1...n-1}. A zero r would turn
+// h^r into 1 and leave the cipher without randomness, so it is drawn again.
+func randomR(random io.Reader, n *big.Int) (*big.Int, error) {
+	for {
+		r, err := uniformBelow(random, n)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() != 0 {
+			return r, nil
+		}
+	}
+}
+
+// uniformBelow returns a uniformly random integer from [0, n) for n > 0. It
+// reads just enough bytes for the bit length of n-1, masks the excess bits of
+// the first byte and retries whenever the value is not below n, so no value
+// is favored by a modular reduction. Each try succeeds with probability above
+// 1/2. A nil or non-positive n is rejected with ErrInvalidScalar.
+func uniformBelow(random io.Reader, n *big.Int) (*big.Int, error) {
+	if n == nil || n.Sign() <= 0 {
+		return nil, ErrInvalidScalar
+	}
+	max := new(big.Int).Sub(n, one)
+	bits := max.BitLen()
+	if bits == 0 {
+		return new(big.Int), nil
+	}
+
+	buf := make([]byte, (bits+7)/8)
+	mask := byte(1<<(bits-8*(len(buf)-1)) - 1)
+	x := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return nil, err
+		}
+		buf[0] &= mask
+		if x.SetBytes(buf).Cmp(n) < 0 {
+			return x, nil
+		}
+	}
+}
+
+// cipherInt converts the passed cipher to an integer. Leading zero bytes are
+// ignored, so stripped and zero-padded ciphers are treated identically. It
+// returns ErrLargeCipher if cipher value is larger than modulus N.
+func (pub *PublicKey) cipherInt(c []byte) (*big.Int, error) {
+	cipher := new(big.Int).SetBytes(c)
+	if cipher.Cmp(pub.N) == 1 { // c < N
+		return nil, ErrLargeCipher
+	}
+	return cipher, nil
+}
+
+// ciphertextSize returns the byte length of the fixed-width cipher encoding.
+func (pub *PublicKey) ciphertextSize() int {
+	return (pub.N.BitLen() + 7) / 8
+}
+
+// fixedWidth left-pads the passed cipher with zeros to ciphertextSize. It
+// returns ErrLargeCipher if the cipher does not fit.
+func (pub *PublicKey) fixedWidth(c []byte) ([]byte, error) {
+	cipher, err := pub.cipherInt(c)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.FillBytes(make([]byte, pub.ciphertextSize())), nil
+}
+
+// cloneInt returns an independent copy of x, keeping nil as nil.
+func cloneInt(x *big.Int) *big.Int {
+	if x == nil {
+		return nil
+	}
+	return new(big.Int).Set(x)
+}
+
+// cloneBytes returns an independent copy of b, keeping nil as nil.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
 }
 
 // GenerateKey generats the private key of the Okamoto-Uchiyama cryptosystem.
+// The primes p and q get bits/2 and bits - bits/2 bits, so p*q has exactly
+// bits bits for odd sizes too, while N = p^2 * q has about bits + bits/2 bits.
 func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
+	return generateKey(random, bits, rand.Prime, nil)
+}
+
+// GenerateKeyWithProgress generates the private key like GenerateKey and
+// calls progress with the stages "generating p", "generating q", "searching g"
+// and "computing h" as key generation reaches them. "computing h" repeats for
+// every candidate g that is rejected. A nil progress is ignored.
+func GenerateKeyWithProgress(random io.Reader, bits int, progress func(stage string)) (*PrivateKey, error) {
+	return generateKey(random, bits, rand.Prime, progress)
+}
+
+// GenerateSafeKey generates the private key of the Okamoto-Uchiyama
+// cryptosystem with safe primes p and q, i.e. (p-1)/2 and (q-1)/2 are also
+// prime. Safe primes are rare, so it is considerably slower than GenerateKey.
+func GenerateSafeKey(random io.Reader, bits int) (*PrivateKey, error) {
+	return generateKey(random, bits, safePrime, nil)
+}
+
+// generateKey generates the private key using prime to choose p and q. It
+// reports the stages to progress unless it is nil.
+func generateKey(random io.Reader, bits int, prime func(io.Reader, int) (*big.Int, error), progress func(string)) (*PrivateKey, error) {
+	debugf("okamoto-uchiyama: generating key with %d-bit p*q", bits)
+	stage := func(name string) {
+		if progress != nil {
+			progress(name)
+		}
+	}
+
 	// prime number p
-	p, err := rand.Prime(random, bits/2)
+	stage("generating p")
+	p, err := prime(random, bits/2)
 	if err != nil {
 		return nil, err
 	}
+	debugf("okamoto-uchiyama: found %d-bit prime p", bits/2)
 
 	// prime number q
-	q, err := rand.Prime(random, bits/2)
+	stage("generating q")
+	q, err := prime(random, bits-bits/2)
 	if err != nil {
 		return nil, err
 	}
+	debugf("okamoto-uchiyama: found %d-bit prime q", bits-bits/2)
 
 	// psquare = p * p
 	psquare := new(big.Int).Mul(p, p)
 	// n = psquare * q
 	n := new(big.Int).Mul(psquare, q)
 
-	// randomly choosing ineger g from {2...n-1},
-	// such that g^(p-1) mod p^2 != 1
-	var g, gpminuse1 *big.Int
+	// randomly choosing ineger g from {2...n-1} coprime to n, such that
+	// g^(p-1) mod p^2 != 1, its L value is invertible and g^n mod n != 1
+	var g, gpminuse1, h *big.Int
+	stage("searching g")
 	for {
 		pminuse1 := new(big.Int).Sub(p, one)
-		g, err = rand.Int(rand.Reader, new(big.Int).Sub(n, one))
+		g, err = rand.Int(random, new(big.Int).Sub(n, one))
 		if err != nil {
 			return nil, err
 		}
+		// a multiple of p or q breaks the subgroup structure
+		if !coprime(g, n) {
+			continue
+		}
 
 		gpminuse1 = new(big.Int).Mod(
 			new(big.Int).Exp(g, pminuse1, psquare),
 			psquare,
 		)
 
-		if gpminuse1.Cmp(one) != 0 {
+		// h = g^n mod n
+		stage("computing h")
+		h = new(big.Int).Mod(
+			new(big.Int).Exp(g, n, n),
+			n,
+		)
+
+		if acceptGenerator(gpminuse1, h, p) {
 			break
 		}
+		debugf("okamoto-uchiyama: rejected generator candidate")
+	}
+
+	priv := newPrivateKey(p, psquare, n, g, gpminuse1, h)
+	if err := priv.commitPlaintextBound(random); err != nil {
+		return nil, err
+	}
+	debugf("okamoto-uchiyama: generated key with %d-bit modulus N", n.BitLen())
+	return priv, nil
+}
+
+// GenerateKeyFromPrimes builds the private key from the caller-chosen primes
+// p and q and generator g, e.g. to reproduce worked examples with small
+// hand-picked values. It returns ErrInvalidKey if p or q is not prime, they
+// are equal, or g is not in {2...n-1}, coprime to n and with
+// g^(p-1) mod p^2 != 1.
+//
+// The scheme follows T. Okamoto and S. Uchiyama, "A New Public-Key
+// Cryptosystem as Secure as Factoring", EUROCRYPT '98, LNCS 1403, pp.
+// 308-318. To reproduce a worked example exactly, encrypt with the fixed r
+// of the example using EncryptWithR. The following toy example is not taken
+// from the paper but computed with this package: p = 11, q = 13 and g = 2
+// give N = 1573 and h = 2^N mod N = 1328, and m = 5 with r = 7 encrypts to
+// c = 2^5 * 1328^7 mod N = 196.
+func GenerateKeyFromPrimes(p, q, g *big.Int) (*PrivateKey, error) {
+	if !p.ProbablyPrime(20) || !q.ProbablyPrime(20) || p.Cmp(q) == 0 {
+		return nil, ErrInvalidKey
+	}
+
+	// psquare = p * p
+	psquare := new(big.Int).Mul(p, p)
+	// n = psquare * q
+	n := new(big.Int).Mul(psquare, q)
+	if g.Cmp(one) <= 0 || g.Cmp(n) >= 0 || !coprime(g, n) {
+		return nil, ErrInvalidKey
 	}
 
+	// g^(p-1) mod p^2
+	pminuse1 := new(big.Int).Sub(p, one)
+	gpminuse1 := new(big.Int).Exp(g, pminuse1, psquare)
+
 	// h = g^n mod n
-	h := new(big.Int).Mod(
-		new(big.Int).Exp(g, n, n),
-		n,
-	)
+	h := new(big.Int).Exp(g, n, n)
+	if !acceptGenerator(gpminuse1, h, p) {
+		return nil, ErrInvalidKey
+	}
+	return newPrivateKey(
+		new(big.Int).Set(p), psquare, n, new(big.Int).Set(g), gpminuse1, h,
+	), nil
+}
+
+// newPrivateKey assembles the private key from its chosen values.
+func newPrivateKey(p, psquare, n, g, gpminuse1, h *big.Int) *PrivateKey {
 	return &PrivateKey{
 		PublicKey: PublicKey{
 			N: n,
 			G: g,
 			H: h,
+
+			PlaintextBound: plaintextBound(p),
 		},
 		GD:       gpminuse1,
 		P:        p,
 		PSquared: psquare,
-	}, nil
+	}
 }
 
 // Encrypt encrypts a plain text represented as a byte array. It returns
-// an error if plain text value is larger than modulus N of Public key.
+// an error if plain text value is not below the PlaintextBound of Public
+// key, or larger than its modulus N if the bound is unset.
 func (pub *PublicKey) Encrypt(plainText []byte) ([]byte, error) {
 	// choose a random integer r from {1...n-1}
-	r, err := rand.Int(rand.Reader, new(big.Int).Sub(pub.N, one))
+	r, err := randomR(Rand, pub.N)
 	if err != nil {
 		return nil, err
 	}
 
 	m := new(big.Int).SetBytes(plainText)
-	if m.Cmp(pub.N) == 1 { //  m < N
+	if !pub.PlaintextFits(m) {
 		return nil, ErrLargeMessage
 	}
 
+	debugf("okamoto-uchiyama: encrypting under %d-bit modulus N", pub.N.BitLen())
+	return pub.encrypt(m, r).Bytes(), nil
+}
+
+// EncryptFixedWidth encrypts a plain text like Encrypt and left-pads the
+// cipher with zeros to the byte length of N. Every cipher then has the same
+// length, whatever the plain text, so the length cannot reveal e.g. a zero
+// plain text, whose cipher is just h^r. The unpadded ciphers of Encrypt are
+// shorter by a byte with probability about 1/256, but that only depends on
+// the random r, not on the plain text.
+func (pub *PublicKey) EncryptFixedWidth(plainText []byte) ([]byte, error) {
+	cipher, err := pub.Encrypt(plainText)
+	if err != nil {
+		return nil, err
+	}
+	return pub.fixedWidth(cipher)
+}
+
+// PlaintextFits reports whether Encrypt accepts m: it must be non-negative
+// and below the PlaintextBound of Public key, or not larger than its modulus
+// N if the bound is unset.
+func (pub *PublicKey) PlaintextFits(m *big.Int) bool {
+	if m.Sign() < 0 || m.Cmp(pub.N) == 1 { //  m < N
+		return false
+	}
+	if pub.PlaintextBound != nil && m.Cmp(pub.PlaintextBound) >= 0 { // m < bound
+		return false
+	}
+	return true
+}
+
+// checkSignedPlaintext checks that a signed m, encrypted mod N like
+// EncryptMod, decrypts again with DecryptSigned. Its magnitude must be below
+// half the PlaintextBound, i.e. 2^(bitlen(p)-2) <= (p-1)/2, since larger
+// values wrap around. It returns ErrNoPlaintextBound if the bound is unset
+// and ErrLargeMessage if m is out of range.
+func (pub *PublicKey) checkSignedPlaintext(m *big.Int) error {
+	if pub.PlaintextBound == nil {
+		return ErrNoPlaintextBound
+	}
+	half := new(big.Int).Rsh(pub.PlaintextBound, 1)
+	if new(big.Int).Abs(m).Cmp(half) >= 0 { // |m| < bound/2
+		return ErrLargeMessage
+	}
+	return nil
+}
+
+// RandomPlaintext returns a uniformly random plain text read from random in
+// [0, PlaintextBound), which Encrypt accepts and Decrypt recovers, e.g. for
+// fuzzing round-trips. It returns ErrNoPlaintextBound if the bound is unset,
+// since the modulus N alone does not tell which values decrypt correctly.
+func (pub *PublicKey) RandomPlaintext(random io.Reader) (*big.Int, error) {
+	if pub.PlaintextBound == nil {
+		return nil, ErrNoPlaintextBound
+	}
+	return rand.Int(random, pub.PlaintextBound)
+}
+
+// EncryptMod encrypts the integer m reduced into the plain text space
+// instead of rejecting it. Since p divides N, m is reduced mod N, which keeps
+// m mod p and also maps negative values. Decryption returns m mod p, so any
+// information above the secret prime p is lost.
+func (pub *PublicKey) EncryptMod(m *big.Int) ([]byte, error) {
+	// choose a random integer r from {1...n-1}
+	r, err := randomR(Rand, pub.N)
+	if err != nil {
+		return nil, err
+	}
+	return pub.encrypt(new(big.Int).Mod(m, pub.N), r).Bytes(), nil
+}
+
+// EncryptWithR encrypts a plain text like Encrypt, but with the caller
+// supplied random value r, which must be in [1, N-1]. The same plain text and
+// r always give the same cipher, which is meant for proofs and test vectors;
+// reusing r across different plain texts leaks their difference.
+func (pub *PublicKey) EncryptWithR(plainText []byte, r *big.Int) ([]byte, error) {
+	if r.Sign() <= 0 || r.Cmp(pub.N) >= 0 {
+		return nil, ErrInvalidRandom
+	}
+
+	m := new(big.Int).SetBytes(plainText)
+	if !pub.PlaintextFits(m) {
+		return nil, ErrLargeMessage
+	}
+
+	return pub.encrypt(m, r).Bytes(), nil
+}
+
+// encrypt computes the cipher of m with the random value r.
+func (pub *PublicKey) encrypt(m, r *big.Int) *big.Int {
 	// c = g^m * h^r mod N
-	c := new(big.Int).Mod(
+	return new(big.Int).Mod(
 		new(big.Int).Mul(
 			new(big.Int).Exp(pub.G, m, pub.N),
 			new(big.Int).Exp(pub.H, r, pub.N),
 		),
 		pub.N,
 	)
-	return c.Bytes(), nil
+}
+
+// PowG returns g^exp mod N, e.g. to check that PowG(N) equals H. A
+// negative exp uses the inverse of g, and nil is returned if g is not
+// invertible mod N.
+func (pub *PublicKey) PowG(exp *big.Int) *big.Int {
+	x, err := pub.gPow(exp)
+	if err != nil {
+		return nil
+	}
+	// a copy, as gPow may return an entry of the PrecomputeConstants table
+	return new(big.Int).Set(x)
+}
+
+// PowH returns h^exp mod N like PowG.
+func (pub *PublicKey) PowH(exp *big.Int) *big.Int {
+	x, err := pub.hPow(exp)
+	if err != nil {
+		return nil
+	}
+	return x
+}
+
+// EncryptWithCommitment encrypts a plain text like Encrypt and also
+// returns a commitment to the cipher, which is SHA-256 over its fixed-width
+// encoding. The commitment can be published before the cipher is revealed
+// and checked later with VerifyCommitment.
+func (pub *PublicKey) EncryptWithCommitment(plainText []byte) (cipher []byte, commitment []byte, err error) {
+	cipher, err = pub.Encrypt(plainText)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fixed, err := pub.fixedWidth(cipher)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(fixed)
+	return cipher, sum[:], nil
+}
+
+// VerifyCommitment reports whether commitment was produced for the passed
+// cipher by EncryptWithCommitment.
+func (pub *PublicKey) VerifyCommitment(cipher, commitment []byte) bool {
+	fixed, err := pub.fixedWidth(cipher)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(fixed)
+	return subtle.ConstantTimeCompare(sum[:], commitment) == 1
+}
+
+// EncryptZero returns a fresh encryption of zero, c = h^r mod N. It is the
+// identity of the homomorphic addition and hides the result it is folded into.
+func (pub *PublicKey) EncryptZero() ([]byte, error) {
+	return pub.Encrypt(nil)
+}
+
+// ComputeGD computes GD = G^(p-1) mod p^2 from the prime P and the public
+// key. It lets a key whose GD was not stored be restored.
+func (priv *PrivateKey) ComputeGD() *big.Int {
+	pminuse1 := new(big.Int).Sub(priv.P, one)
+	return new(big.Int).Exp(priv.G, pminuse1, priv.PSquared)
+}
+
+// SyncPublic re-derives the embedded public key from the private values
+// after manual edits: Q = N / PSquared, N = P^2 * Q, H = G^N mod N and the
+// plaintext bound from P with its commitment. It returns ErrInvalidKey if
+// PSquared is not P^2 or does not divide N, leaving the key unchanged. The
+// tables built by Precompute and PrecomputeConstants are dropped, as N may
+// have changed.
+func (priv *PrivateKey) SyncPublic() error {
+	if !positive(priv.N, priv.G, priv.P, priv.PSquared) {
+		return ErrInvalidKey
+	}
+	if new(big.Int).Mul(priv.P, priv.P).Cmp(priv.PSquared) != 0 {
+		return ErrInvalidKey
+	}
+	q, rem := new(big.Int).QuoRem(priv.N, priv.PSquared, new(big.Int))
+	if rem.Sign() != 0 || q.Cmp(one) <= 0 {
+		return ErrInvalidKey
+	}
+
+	// n = psquare * q
+	n := new(big.Int).Mul(priv.PSquared, q)
+
+	// h = g^n mod n
+	priv.H = new(big.Int).Exp(priv.G, n, n)
+	priv.N = n
+	priv.PlaintextBound = plaintextBound(priv.P)
+	priv.recommitPlaintextBound()
+	priv.precomputed = nil
+	priv.constants = nil
+	return nil
+}
+
+// MaxSafeSum returns P-1, the largest plain text the key can represent
+// before decryption wraps mod p. Unlike the public PlaintextBound it uses the
+// secret prime, so it lets the key holder configure aggregation bounds
+// exactly.
+func (priv *PrivateKey) MaxSafeSum() *big.Int {
+	return new(big.Int).Sub(priv.P, one)
+}
+
+// PlaintextUtilization decrypts the passed cipher and returns the bit length
+// of its plain text as a fraction of the bit length of p, to diagnose sums
+// approaching overflow. Values near 1 are about to wrap mod p; since every
+// plain text is reduced mod p, an overflowed sum shows up as an arbitrary
+// value and cannot be told apart.
+func (priv *PrivateKey) PlaintextUtilization(c []byte) (float64, error) {
+	m, err := priv.decrypt(c)
+	if err != nil {
+		return 0, err
+	}
+	return float64(m.BitLen()) / float64(priv.P.BitLen()), nil
+}
+
+// SubgroupParams returns GD = g^(p-1) mod p^2 and l2 = L(GD) = (GD - 1) / p.
+// GD lies in the subgroup of order p of (Z/p^2Z)*, where every element is
+// 1 + k*p for a unique k mod p, and l2 is that k for GD. Decryption divides
+// L(c^(p-1) mod p^2) by l2 mod p to recover the plain text.
+func (priv *PrivateKey) SubgroupParams() (gd *big.Int, l2 *big.Int) {
+	return new(big.Int).Set(priv.GD), lFunction(priv.GD, priv.P)
 }
 
 // Decrypt decrypts the passed cipher text. It returns an
 // error if ciphe text value is larger than modulus N of Public key.
+// Leading zero bytes of the cipher text are ignored.
 func (priv *PrivateKey) Decrypt(cipherText []byte) ([]byte, error) {
+	m, err := priv.decrypt(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	return m.Bytes(), nil
+}
+
+// strictSlackBits is how many bits shorter than N a cipher may be in
+// DecryptStrict. A uniformly random element of the group mod N is shorter
+// with probability about 2^-64.
+const strictSlackBits = 64
+
+// DecryptStrict decrypts the passed cipher text like Decrypt, but first
+// rejects ciphers whose bit length is far below that of N with
+// ErrImplausibleCiphertext. Honest ciphers are random-looking elements near N
+// in magnitude, so a tiny cipher likely shows corruption or probing. This is
+// only a heuristic: it does not detect crafted ciphers of plausible size and
+// gives no integrity, since ciphers are malleable by design.
+func (priv *PrivateKey) DecryptStrict(cipherText []byte) ([]byte, error) {
 	c := new(big.Int).SetBytes(cipherText)
-	if c.Cmp(priv.N) == 1 { // c < N
-		return nil, ErrLargeCipher
+	if c.BitLen() < priv.N.BitLen()-strictSlackBits {
+		return nil, ErrImplausibleCiphertext
 	}
-	pminuse1 := new(big.Int).Sub(priv.P, one)
+	return priv.Decrypt(cipherText)
+}
 
-	// c^(p-1) mod p^2
-	a := new(big.Int).Exp(c, pminuse1, priv.PSquared)
+// DecryptFixed decrypts the passed cipher text like Decrypt and left-pads the
+// plain text with zeros to exactly size bytes. It returns ErrPlaintextSize
+// if the plain text needs more than size bytes.
+func (priv *PrivateKey) DecryptFixed(cipherText []byte, size int) ([]byte, error) {
+	m, err := priv.decrypt(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 || (m.BitLen()+7)/8 > size {
+		return nil, ErrPlaintextSize
+	}
+	return m.FillBytes(make([]byte, size)), nil
+}
 
-	// L1(a) = (a - 1) / p
-	l1 := new(big.Int).Div(
-		new(big.Int).Sub(a, one),
-		priv.P,
-	)
+// precomputedValues are per-key values of the decryption.
+type precomputedValues struct {
+	pminuse1 *big.Int
+	binverse *big.Int
+	q        *big.Int
+}
+
+// Precompute caches the values decryption derives from the key alone, p-1
+// and the inverse of L(GD) mod p, saving a modular inversion per Decrypt, and
+// the prime q returned by Q.
+// math/big does not expose a reusable Montgomery context, so the
+// exponentiation c^(p-1) mod p^2 itself cannot be sped up this way. It must be
+// called again after the key fields are changed, and not concurrently with
+// other uses of the key.
+func (priv *PrivateKey) Precompute() {
+	pminuse1, binverse := priv.computeDecryptionValues()
+	priv.precomputed = &precomputedValues{
+		pminuse1: pminuse1,
+		binverse: binverse,
+		q:        priv.computeQ(),
+	}
+}
+
+// Q returns the prime q = N / PSquared that appears once in N, which the key
+// does not store. It is cached by Precompute, and computed on each call
+// otherwise. It returns nil if PSquared is not positive.
+func (priv *PrivateKey) Q() *big.Int {
+	if pc := priv.precomputed; pc != nil && pc.q != nil {
+		return new(big.Int).Set(pc.q)
+	}
+	return priv.computeQ()
+}
+
+// computeQ computes q = N / PSquared.
+func (priv *PrivateKey) computeQ() *big.Int {
+	if !positive(priv.N, priv.PSquared) {
+		return nil
+	}
+	return new(big.Int).Div(priv.N, priv.PSquared)
+}
+
+// decryptionValues returns p-1 and L(GD)^(-1) mod p, from the cache if set.
+func (priv *PrivateKey) decryptionValues() (pminuse1, binverse *big.Int) {
+	if pc := priv.precomputed; pc != nil {
+		return pc.pminuse1, pc.binverse
+	}
+	return priv.computeDecryptionValues()
+}
+
+// computeDecryptionValues computes p-1 and L(GD)^(-1) mod p. binverse is
+// nil if the key is corrupt, i.e. P, GD or PSquared is not positive or L(GD)
+// is not invertible mod p.
+func (priv *PrivateKey) computeDecryptionValues() (pminuse1, binverse *big.Int) {
+	if !positive(priv.P, priv.GD, priv.PSquared) {
+		return nil, nil
+	}
+	pminuse1 = new(big.Int).Sub(priv.P, one)
 
 	// L2(b) = (b-1) / p
-	l2 := new(big.Int).Div(
-		new(big.Int).Sub(priv.GD, one),
-		priv.P,
-	)
+	l2 := lFunction(priv.GD, priv.P)
 
 	// b^(-1) mod p
-	binverse := new(big.Int).ModInverse(l2, priv.P)
+	binverse = new(big.Int).ModInverse(l2, priv.P)
+	return pminuse1, binverse
+}
+
+// DecryptTo decrypts the passed cipher text like Decrypt and writes the
+// plain text to w instead of returning it. It returns the number of bytes
+// written.
+func (priv *PrivateKey) DecryptTo(w io.Writer, cipherText []byte) (int, error) {
+	m, err := priv.decrypt(cipherText)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(m.Bytes())
+}
+
+// SaturatingAdd decrypts the passed counter cipher, adds delta and encrypts
+// the result clamped to [0, cap] again, so bounded counters cap instead of
+// wrapping mod p. It returns ErrInvalidScalar for a nil delta or a nil or
+// negative cap, and ErrLargeMessage if cap does not fit in the plain text
+// space.
+func (priv *PrivateKey) SaturatingAdd(c []byte, delta *big.Int, cap *big.Int) ([]byte, error) {
+	if delta == nil || cap == nil || cap.Sign() < 0 {
+		return nil, ErrInvalidScalar
+	}
+	if !priv.PlaintextFits(cap) {
+		return nil, ErrLargeMessage
+	}
+	m, err := priv.decrypt(c)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Add(m, delta)
+	if m.Cmp(cap) > 0 {
+		m.Set(cap)
+	} else if m.Sign() < 0 {
+		m.SetInt64(0)
+	}
+	return priv.Encrypt(m.Bytes())
+}
+
+// DecryptSigned decrypts the passed cipher text to a signed integer: plain
+// texts above (p-1)/2 are taken as negative values m - p. Negative values can
+// be encrypted with EncryptMod, and homomorphic sums of signed values decode
+// correctly as long as they stay within (-p/2, p/2).
+func (priv *PrivateKey) DecryptSigned(cipherText []byte) (*big.Int, error) {
+	m, err := priv.decrypt(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	return priv.signed(m), nil
+}
+
+// signed maps a plain text in [0, p) to (-p/2, p/2).
+func (priv *PrivateKey) signed(m *big.Int) *big.Int {
+	half := new(big.Int).Rsh(priv.P, 1) // (p-1)/2
+	if m.Cmp(half) > 0 {
+		return m.Sub(m, priv.P)
+	}
+	return m
+}
+
+// decrypt decrypts the passed cipher text to the plain text integer. It
+// returns ErrInvalidKey instead of panicking if the key is corrupt.
+func (priv *PrivateKey) decrypt(cipherText []byte) (*big.Int, error) {
+	if !positive(priv.N, priv.G, priv.P, priv.PSquared, priv.GD) {
+		return nil, ErrInvalidKey
+	}
+	c, err := priv.cipherInt(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	debugf("okamoto-uchiyama: decrypting %d-byte cipher", len(cipherText))
+	pminuse1, binverse := priv.decryptionValues()
+	if binverse == nil {
+		return nil, ErrInvalidKey
+	}
+
+	// c^(p-1) mod p^2
+	a := new(big.Int).Exp(c, pminuse1, priv.PSquared)
+
+	// L1(a) = (a - 1) / p
+	l1 := lFunction(a, priv.P)
 
 	// m = L(a*b^(-1) mod p^2) mod p
 	m := new(big.Int).Mod(
 		new(big.Int).Mul(l1, binverse),
 		priv.P,
 	)
-	return m.Bytes(), nil
+	return m, nil
+}
+
+// lFunction computes L(x) = (x - 1) / p.
+func lFunction(x, p *big.Int) *big.Int {
+	return new(big.Int).Div(
+		new(big.Int).Sub(x, one),
+		p,
+	)
+}
+
+// coprime reports whether gcd(a, b) = 1.
+func coprime(a, b *big.Int) bool {
+	return new(big.Int).GCD(nil, nil, a, b).Cmp(one) == 0
+}
+
+// ciphertextInverse returns c^(-1) mod n, or ErrInvalidCiphertext if c is
+// not coprime with n. Homomorphic subtraction and negation go through it.
+func ciphertextInverse(c, n *big.Int) (*big.Int, error) {
+	inverse := new(big.Int).ModInverse(c, n)
+	if inverse == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return inverse, nil
+}
+
+// acceptGenerator reports whether a candidate g, given gd = g^(p-1) mod p^2
+// and h = g^n mod n, is accepted by the key generation, i.e. gd != 1,
+// L(gd) is invertible mod p and h != 1.
+func acceptGenerator(gd, h, p *big.Int) bool {
+	if gd.Cmp(one) == 0 || h.Cmp(one) == 0 {
+		return false
+	}
+	return new(big.Int).ModInverse(lFunction(gd, p), p) != nil
+}
+
+// Validate checks that the private key is consistent and its generator is
+// suitable: GD = G^(p-1) mod p^2, H = G^N mod N, H != 1 and L(GD) is
+// invertible mod p, in addition to the checks of PublicKey.Validate. It
+// returns ErrSquaredPrimeMismatch if P^2 is not PSquared or does not divide
+// N, and ErrInvalidKey for the other checks.
+func (priv *PrivateKey) Validate() error {
+	if err := priv.PublicKey.Validate(); err != nil {
+		return err
+	}
+	if !positive(priv.GD, priv.P, priv.PSquared) {
+		return ErrInvalidKey
+	}
+	if err := priv.checkSquaredPrime(); err != nil {
+		return err
+	}
+	if priv.ComputeGD().Cmp(priv.GD) != 0 {
+		return ErrInvalidKey
+	}
+	if new(big.Int).Exp(priv.G, priv.N, priv.N).Cmp(priv.H) != 0 {
+		return ErrInvalidKey
+	}
+	if !acceptGenerator(priv.GD, priv.H, priv.P) {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// CheckCiphertext reports whether the passed cipher text is a valid
+// element of the multiplicative group mod N, i.e. it lies in [1, N-1]
+// and is coprime with N. It returns ErrInvalidCiphertext otherwise.
+func (priv *PrivateKey) CheckCiphertext(cipherText []byte) error {
+	c := new(big.Int).SetBytes(cipherText)
+	if c.Sign() == 0 || c.Cmp(priv.N) >= 0 {
+		return ErrInvalidCiphertext
+	}
+
+	// gcd(c, N) == 1
+	if new(big.Int).GCD(nil, nil, c, priv.N).Cmp(one) != 0 {
+		return ErrInvalidCiphertext
+	}
+	return nil
 }
 
 // HomomorphicEncTwo performs homomorphic operation over two passed chiphers.
 // Okamoto-Uchiyama has additive homomorphic property, so resultant cipher
 // contains the sum of two numbers.
 func (pub *PublicKey) HomomorphicEncTwo(c1, c2 []byte) ([]byte, error) {
-	cipherA := new(big.Int).SetBytes(c1)
-	cipherB := new(big.Int).SetBytes(c2)
-	if cipherA.Cmp(pub.N) == 1 && cipherB.Cmp(pub.N) == 1 { // c < N
-		return nil, ErrLargeCipher
+	cipherA, err := pub.cipherInt(c1)
+	if err != nil {
+		return nil, err
+	}
+	cipherB, err := pub.cipherInt(c2)
+	if err != nil {
+		return nil, err
 	}
 
 	// C = c1*c2 mod N
@@ -167,15 +871,252 @@ func (pub *PublicKey) HommorphicEncMultiple(ciphers ...[]byte) ([]byte, error) {
 	C := one
 
 	for i := 0; i < len(ciphers); i++ {
-		cipher := new(big.Int).SetBytes(ciphers[i])
-		if cipher.Cmp(pub.N) == 1 { // c < N
-			return nil, ErrLargeCipher
+		cipher, err := pub.cipherInt(ciphers[i])
+		if err != nil {
+			return nil, err
+		}
+		// C = c1*c2*c3...cn mod N
+		C = new(big.Int).Mod(
+			new(big.Int).Mul(C, cipher),
+			pub.N,
+		)
+	}
+	return C.Bytes(), nil
+}
+
+// HomomorphicEncMultipleLenient is like HommorphicEncMultiple, but skips the
+// ciphers larger than modulus N instead of failing. It returns the sum of
+// the valid ciphers and the indices of the skipped ones in increasing order,
+// or ErrNoCiphers if no cipher is valid.
+func (pub *PublicKey) HomomorphicEncMultipleLenient(ciphers ...[]byte) (result []byte, skipped []int, err error) {
+	C := one
+	folded := 0
+
+	for i := 0; i < len(ciphers); i++ {
+		cipher, err := pub.cipherInt(ciphers[i])
+		if err != nil {
+			skipped = append(skipped, i)
+			continue
 		}
 		// C = c1*c2*c3...cn mod N
 		C = new(big.Int).Mod(
 			new(big.Int).Mul(C, cipher),
 			pub.N,
 		)
+		folded++
+	}
+	if folded == 0 {
+		return nil, skipped, ErrNoCiphers
+	}
+	return C.Bytes(), skipped, nil
+}
+
+// ReRandomize re-randomizes the passed cipher by multiplying it with h^r
+// for a fresh random r. The resultant cipher decrypts to the same plain
+// text but cannot be linked to the original one.
+func (pub *PublicKey) ReRandomize(c []byte) ([]byte, error) {
+	// choose a random integer r from {1...n-1}
+	r, err := randomR(Rand, pub.N)
+	if err != nil {
+		return nil, err
+	}
+	return pub.ReRandomizeWithR(c, r)
+}
+
+// ReRandomizeWithR re-randomizes the passed cipher with the given r, which
+// must be in [1, N-1]. The same c and r always give the same cipher.
+func (pub *PublicKey) ReRandomizeWithR(c []byte, r *big.Int) ([]byte, error) {
+	cipher, err := pub.cipherInt(c)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sign() <= 0 || r.Cmp(pub.N) >= 0 {
+		return nil, ErrInvalidRandom
+	}
+
+	// C = c * h^r mod N
+	C := new(big.Int).Mod(
+		new(big.Int).Mul(cipher, new(big.Int).Exp(pub.H, r, pub.N)),
+		pub.N,
+	)
+	return C.Bytes(), nil
+}
+
+// Reencrypt decrypts the passed cipher and encrypts its plain text again
+// with fresh randomness. Unlike ReRandomize, whose output is the input times
+// an encryption of zero and so stays related to it by a known product for
+// anyone holding the randomness, the new cipher has no homomorphic relation
+// to the old one at all. It needs the private key for this.
+func (priv *PrivateKey) Reencrypt(c []byte) ([]byte, error) {
+	m, err := priv.decrypt(c)
+	if err != nil {
+		return nil, err
+	}
+	return priv.Encrypt(m.Bytes())
+}
+
+// HomomorphicScalarMul multiplies the plain text of the passed cipher by the
+// non-negative public scalar k. The resultant cipher contains k*m.
+func (pub *PublicKey) HomomorphicScalarMul(c []byte, k *big.Int) ([]byte, error) {
+	cipher, err := pub.cipherInt(c)
+	if err != nil {
+		return nil, err
+	}
+	if k.Sign() < 0 {
+		return nil, ErrInvalidScalar
+	}
+
+	// C = c^k mod N
+	C := new(big.Int).Exp(cipher, k, pub.N)
+	return C.Bytes(), nil
+}
+
+// HomomorphicScalarMulChecked multiplies the plain text of the passed cipher
+// by k like HomomorphicScalarMul, given that the plain text is at most
+// termBound. It returns ErrPlaintextOverflow if k*termBound could reach the
+// PlaintextBound of the public key, beyond which decryption would wrap, and
+// ErrNoPlaintextBound if the bound is unset.
+func (pub *PublicKey) HomomorphicScalarMulChecked(c []byte, k, termBound *big.Int) ([]byte, error) {
+	if pub.PlaintextBound == nil {
+		return nil, ErrNoPlaintextBound
+	}
+	if k.Sign() < 0 || termBound.Sign() < 0 {
+		return nil, ErrInvalidScalar
+	}
+	if new(big.Int).Mul(k, termBound).Cmp(pub.PlaintextBound) >= 0 {
+		return nil, ErrPlaintextOverflow
+	}
+	return pub.HomomorphicScalarMul(c, k)
+}
+
+// HomomorphicScaleRational scales the plain text of the passed cipher by the
+// fraction num/den. Division is not homomorphic, so the resultant cipher only
+// contains num*m and the returned divisor den must be applied by the caller
+// after decryption, e.g. enc(100) scaled by 3/4 decrypts to 300, and 300/4 = 75.
+func (pub *PublicKey) HomomorphicScaleRational(c []byte, num, den *big.Int) ([]byte, *big.Int, error) {
+	if den.Sign() <= 0 {
+		return nil, nil, ErrInvalidScalar
+	}
+
+	C, err := pub.HomomorphicScalarMul(c, num)
+	if err != nil {
+		return nil, nil, err
+	}
+	return C, new(big.Int).Set(den), nil
+}
+
+// HomomorphicWeightedSum computes the weighted sum of the passed ciphers with
+// the non-negative public weights. The resultant cipher contains
+// w1*m1 + w2*m2 + ... + wn*mn.
+func (pub *PublicKey) HomomorphicWeightedSum(ciphers [][]byte, weights []*big.Int) ([]byte, error) {
+	if len(ciphers) != len(weights) {
+		return nil, ErrLengthMismatch
+	}
+
+	C := one
+	for i := 0; i < len(ciphers); i++ {
+		cipher, err := pub.HomomorphicScalarMul(ciphers[i], weights[i])
+		if err != nil {
+			return nil, err
+		}
+		// C = c1^w1 * c2^w2 * ... * cn^wn mod N
+		C = new(big.Int).Mod(
+			new(big.Int).Mul(C, new(big.Int).SetBytes(cipher)),
+			pub.N,
+		)
+	}
+	return C.Bytes(), nil
+}
+
+// HomomorphicWeightedMeanParts returns the parts of a weighted mean of the
+// passed ciphers: the cipher of the weighted sum w1*m1 + ... + wn*mn as
+// numerator and the public total w1 + ... + wn as denominator, which the key
+// holder divides after decrypting. It returns ErrInvalidScalar if the weights
+// sum to zero.
+func (pub *PublicKey) HomomorphicWeightedMeanParts(ciphers [][]byte, weights []*big.Int) (num []byte, denom *big.Int, err error) {
+	num, err = pub.HomomorphicWeightedSum(ciphers, weights)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	denom = new(big.Int)
+	for _, w := range weights {
+		denom.Add(denom, w)
+	}
+	if denom.Sign() == 0 {
+		return nil, nil, ErrInvalidScalar
+	}
+	return num, denom, nil
+}
+
+// DecryptDotProduct computes the weighted sum of the passed ciphers with the
+// public weights homomorphically and decrypts the result.
+func (priv *PrivateKey) DecryptDotProduct(ciphers [][]byte, weights []*big.Int) (*big.Int, error) {
+	C, err := priv.HomomorphicWeightedSum(ciphers, weights)
+	if err != nil {
+		return nil, err
+	}
+	return priv.decrypt(C)
+}
+
+// ConstantTimeEqual compares two decrypted plain texts in time that depends
+// only on their lengths, not on their contents, so comparing a decryption
+// against an expected secret does not leak how many bytes matched. Decrypt
+// strips leading zeros, so both values are compared as integers: the
+// shorter one is left-padded with zeros first.
+func ConstantTimeEqual(a, b []byte) bool {
+	size := len(a)
+	if len(b) > size {
+		size = len(b)
+	}
+
+	x := make([]byte, size)
+	y := make([]byte, size)
+	copy(x[size-len(a):], a)
+	copy(y[size-len(b):], b)
+	return subtle.ConstantTimeCompare(x, y) == 1
+}
+
+// HomomorphicPolyEval evaluates the polynomial with encrypted coefficients
+// at the non-negative public point x, where coeffs[i] encrypts the
+// coefficient of t^i. The resultant cipher contains a0 + a1*x + ... + an*x^n,
+// computed by Horner's rule. An empty polynomial gives a fresh encryption of
+// zero.
+func (pub *PublicKey) HomomorphicPolyEval(coeffs [][]byte, x *big.Int) ([]byte, error) {
+	if len(coeffs) == 0 {
+		return pub.EncryptZero()
+	}
+	if x.Sign() < 0 {
+		return nil, ErrInvalidScalar
+	}
+
+	C, err := pub.cipherInt(coeffs[len(coeffs)-1])
+	if err != nil {
+		return nil, err
+	}
+	for i := len(coeffs) - 2; i >= 0; i-- {
+		cipher, err := pub.cipherInt(coeffs[i])
+		if err != nil {
+			return nil, err
+		}
+		// C = C^x * ci mod N
+		C = new(big.Int).Mod(
+			new(big.Int).Mul(new(big.Int).Exp(C, x, pub.N), cipher),
+			pub.N,
+		)
 	}
 	return C.Bytes(), nil
 }
+
+// RotateCiphertext moves the passed cipher from the key priv to the public
+// key newPub by decrypting and encrypting again, preserving the plain text.
+// It needs the old private key, and the result has no homomorphic relation
+// to ciphers that remain under the old key. It returns ErrLargeMessage if the
+// plain text does not fit the new key.
+func RotateCiphertext(priv *PrivateKey, newPub *PublicKey, c []byte) ([]byte, error) {
+	m, err := priv.Decrypt(c)
+	if err != nil {
+		return nil, err
+	}
+	return newPub.Encrypt(m)
+}
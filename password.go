@@ -0,0 +1,58 @@
+package okamotoUchiyama
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// passwordSalt is the fixed salt of GenerateKeyFromPassword.
+var passwordSalt = []byte("okamoto-uchiyama password key")
+
+// passwordIterations is the PBKDF2-HMAC-SHA256 iteration count of
+// GenerateKeyFromPassword.
+const passwordIterations = 600000
+
+// hmacStream is an endless deterministic byte stream, the blocks
+// HMAC-SHA256(seed, counter) for counter = 0, 1, 2, ...
+type hmacStream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (s *hmacStream) Read(b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		if len(s.buf) == 0 {
+			mac := hmac.New(sha256.New, s.seed)
+			mac.Write(binary.BigEndian.AppendUint64(nil, s.counter))
+			s.buf = mac.Sum(nil)
+			s.counter++
+		}
+		c := copy(b[n:], s.buf)
+		s.buf = s.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// GenerateKeyFromPassword deterministically derives a private key from the
+// password: PBKDF2-HMAC-SHA256 stretches it into a seed, which feeds the key
+// generation like GenerateKey as a stream of random bytes. The same password
+// and size always give the same key.
+//
+// The key is only as strong as the password. Anyone can test guesses offline
+// against the public key at the cost of PBKDF2 plus one key generation per
+// guess, so low-entropy passwords are broken quickly. The salt is fixed, as
+// there is nowhere to keep one, so equal passwords give equal keys for all
+// users and guesses can be precomputed once for everyone. Prefer GenerateKey
+// with crypto/rand wherever the key can be stored instead.
+func GenerateKeyFromPassword(password []byte, bits int) (*PrivateKey, error) {
+	seed, err := pbkdf2.Key(sha256.New, string(password), passwordSalt, passwordIterations, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	return generateKey(&hmacStream{seed: seed}, bits, readerPrime, nil)
+}
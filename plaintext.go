@@ -0,0 +1,102 @@
+package okamotoUchiyama
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+var ErrMalformedPlaintext = errors.New("okamoto-uchiyama: decrypted plain text is not length-prefixed")
+
+// lengthPrefix prepends the uvarint length of b to b. The first byte of a
+// non-zero uvarint is never zero, so the prefix survives the conversion to
+// an integer and protects all zeros of b.
+func lengthPrefix(b []byte) []byte {
+	return append(binary.AppendUvarint(nil, uint64(len(b))), b...)
+}
+
+// stripLengthPrefix reverses lengthPrefix on a decrypted plain text.
+func stripLengthPrefix(b []byte) ([]byte, error) {
+	if len(b) == 0 { // the empty plain text encodes to zero
+		return []byte{}, nil
+	}
+
+	n, size := binary.Uvarint(b)
+	if size <= 0 || uint64(len(b)-size) != n {
+		return nil, ErrMalformedPlaintext
+	}
+	return b[size:], nil
+}
+
+// EncryptExact encrypts a plain text such that DecryptExact returns it
+// byte for byte, including leading zeros, by prefixing it with its length
+// inside the plain text integer. The prefix takes up to a few bytes of the
+// plain text space.
+func (pub *PublicKey) EncryptExact(plainText []byte) ([]byte, error) {
+	return pub.EncryptEncoded(plainText, LengthPrefixedEncoder{})
+}
+
+// DecryptExact decrypts a cipher produced by EncryptExact. It returns
+// ErrMalformedPlaintext if the plain text is not length-prefixed.
+func (priv *PrivateKey) DecryptExact(cipherText []byte) ([]byte, error) {
+	return priv.DecryptEncoded(cipherText, LengthPrefixedEncoder{})
+}
+
+// Encoder maps plain text bytes to the plain text integer and back.
+type Encoder interface {
+	Encode([]byte) (*big.Int, error)
+	Decode(*big.Int) ([]byte, error)
+}
+
+// RawEncoder reads the bytes as a big-endian integer, like Encrypt and
+// Decrypt do. Leading zero bytes are lost.
+type RawEncoder struct{}
+
+// Encode returns the big-endian integer of b.
+func (RawEncoder) Encode(b []byte) (*big.Int, error) {
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Decode returns the minimal big-endian bytes of m.
+func (RawEncoder) Decode(m *big.Int) ([]byte, error) {
+	return m.Bytes(), nil
+}
+
+// LengthPrefixedEncoder prefixes the bytes with their uvarint length, like
+// EncryptExact and DecryptExact do, so leading zeros and the exact length
+// survive.
+type LengthPrefixedEncoder struct{}
+
+// Encode returns the integer of the length-prefixed b.
+func (LengthPrefixedEncoder) Encode(b []byte) (*big.Int, error) {
+	return new(big.Int).SetBytes(lengthPrefix(b)), nil
+}
+
+// Decode strips the length prefix from the bytes of m. It returns
+// ErrMalformedPlaintext if m is not length-prefixed.
+func (LengthPrefixedEncoder) Decode(m *big.Int) ([]byte, error) {
+	return stripLengthPrefix(m.Bytes())
+}
+
+// EncryptEncoded encrypts the plain text as encoded by enc. It returns
+// ErrLargeMessage if the encoded integer does not fit like in Encrypt.
+func (pub *PublicKey) EncryptEncoded(plainText []byte, enc Encoder) ([]byte, error) {
+	m, err := enc.Encode(plainText)
+	if err != nil {
+		return nil, err
+	}
+	if !pub.PlaintextFits(m) {
+		return nil, ErrLargeMessage
+	}
+	return pub.Encrypt(m.Bytes())
+}
+
+// DecryptEncoded decrypts the passed cipher and decodes the plain text
+// integer with enc.
+func (priv *PrivateKey) DecryptEncoded(cipherText []byte, enc Encoder) ([]byte, error) {
+	m, err := priv.decrypt(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Decode(m)
+}
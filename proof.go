@@ -0,0 +1,154 @@
+package okamotoUchiyama
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/big"
+)
+
+var ErrWitnessMismatch = errors.New("okamoto-uchiyama: random values do not match the ciphers")
+
+// equalityLabel separates the challenge of the equality proof.
+var equalityLabel = []byte("okamoto-uchiyama equality proof")
+
+// challengeBits is the size of the Fiat-Shamir challenge, and the statistical
+// hiding margin of the response.
+const challengeBits = 128
+
+// EqualityProof is a non-interactive proof that two ciphers encrypt the same
+// plain text. For c1 = g^m * h^r1 and c2 = g^m * h^r2, the quotient
+// D = c1 / c2 = h^(r1-r2) is an encryption of 0, and the proof shows
+// knowledge of d = r1 - r2 with D = h^d: A = h^w for a random w, the
+// challenge e hashes the key, the ciphers and A, and Z = w + e*d.
+type EqualityProof struct {
+	A *big.Int
+	Z *big.Int
+}
+
+// ProveEqual proves that c1 and c2, encrypted with the random values r1 and
+// r2, contain the same plain text, see EqualityProof. The challenge is
+// derived with SHA-256. It returns ErrWitnessMismatch unless
+// c1 / c2 = h^(r1-r2) mod N.
+func (pub *PublicKey) ProveEqual(c1, c2 []byte, r1, r2 *big.Int) (*EqualityProof, error) {
+	return pub.ProveEqualWithHash(sha256.New, c1, c2, r1, r2)
+}
+
+// ProveEqualWithHash is like ProveEqual, but derives the challenge with the
+// passed hash function, which must produce at least 16 bytes. The proof only
+// verifies with VerifyEqualWithHash and the same hash.
+func (pub *PublicKey) ProveEqualWithHash(newHash func() hash.Hash, c1, c2 []byte, r1, r2 *big.Int) (*EqualityProof, error) {
+	D, err := pub.cipherQuotient(c1, c2)
+	if err != nil {
+		return nil, err
+	}
+	d := new(big.Int).Sub(r1, r2)
+	hd, err := pub.hPow(d)
+	if err != nil {
+		return nil, err
+	}
+	if hd.Cmp(D) != 0 {
+		return nil, ErrWitnessMismatch
+	}
+
+	// w hides e*d statistically, since |e*d| < 2^(bits(N) + challengeBits)
+	wBound := new(big.Int).Lsh(one, uint(pub.N.BitLen()+2*challengeBits))
+	for {
+		w, err := rand.Int(Rand, wBound)
+		if err != nil {
+			return nil, err
+		}
+
+		// A = h^w mod N
+		A := new(big.Int).Exp(pub.H, w, pub.N)
+		e := pub.equalityChallenge(newHash, c1, c2, A)
+
+		// Z = w + e*d
+		Z := new(big.Int).Add(w, new(big.Int).Mul(e, d))
+		if Z.Sign() >= 0 {
+			return &EqualityProof{A: A, Z: Z}, nil
+		}
+	}
+}
+
+// VerifyEqual reports whether proof shows that c1 and c2 contain the same
+// plain text under pub, i.e. h^Z = A * (c1/c2)^e mod N with the SHA-256
+// challenge e. Soundness rests on the order of h being unknown, which holds
+// as long as N is not factored.
+func VerifyEqual(pub *PublicKey, c1, c2 []byte, proof *EqualityProof) bool {
+	return VerifyEqualWithHash(pub, sha256.New, c1, c2, proof)
+}
+
+// VerifyEqualWithHash is like VerifyEqual for a proof made by
+// ProveEqualWithHash with the same hash function.
+func VerifyEqualWithHash(pub *PublicKey, newHash func() hash.Hash, c1, c2 []byte, proof *EqualityProof) bool {
+	if proof == nil || proof.Z == nil || proof.Z.Sign() < 0 {
+		return false
+	}
+	if !positive(proof.A) || proof.A.Cmp(pub.N) >= 0 {
+		return false
+	}
+	D, err := pub.cipherQuotient(c1, c2)
+	if err != nil {
+		return false
+	}
+	e := pub.equalityChallenge(newHash, c1, c2, proof.A)
+
+	// h^Z = A * D^e mod N
+	lhs := new(big.Int).Exp(pub.H, proof.Z, pub.N)
+	rhs := new(big.Int).Mod(
+		new(big.Int).Mul(proof.A, new(big.Int).Exp(D, e, pub.N)),
+		pub.N,
+	)
+	return lhs.Cmp(rhs) == 0
+}
+
+// cipherQuotient returns c1 * c2^(-1) mod N.
+func (pub *PublicKey) cipherQuotient(c1, c2 []byte) (*big.Int, error) {
+	cipherA, err := pub.cipherInt(c1)
+	if err != nil {
+		return nil, err
+	}
+	cipherB, err := pub.cipherInt(c2)
+	if err != nil {
+		return nil, err
+	}
+	binverse, err := ciphertextInverse(cipherB, pub.N)
+	if err != nil {
+		return nil, err
+	}
+
+	// D = c1 * c2^(-1) mod N
+	return new(big.Int).Mod(new(big.Int).Mul(cipherA, binverse), pub.N), nil
+}
+
+// hPow returns h^d mod N for any integer d. It returns ErrInvalidKey if a
+// negative d needs the inverse of a corrupt h.
+func (pub *PublicKey) hPow(d *big.Int) (*big.Int, error) {
+	if d.Sign() >= 0 {
+		return new(big.Int).Exp(pub.H, d, pub.N), nil
+	}
+	hinverse := new(big.Int).ModInverse(pub.H, pub.N)
+	if hinverse == nil {
+		return nil, ErrInvalidKey
+	}
+	return new(big.Int).Exp(hinverse, new(big.Int).Neg(d), pub.N), nil
+}
+
+// equalityChallenge hashes the label, N, G, H, the ciphers and A, each
+// length-prefixed, to a challengeBits-bit challenge.
+func (pub *PublicKey) equalityChallenge(newHash func() hash.Hash, c1, c2 []byte, A *big.Int) *big.Int {
+	h := newHash()
+	h.Write(equalityLabel)
+	for _, part := range [][]byte{
+		pub.N.Bytes(), pub.G.Bytes(), pub.H.Bytes(),
+		new(big.Int).SetBytes(c1).Bytes(), new(big.Int).SetBytes(c2).Bytes(),
+		A.Bytes(),
+	} {
+		h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+		h.Write(part)
+	}
+	return new(big.Int).SetBytes(h.Sum(nil)[:challengeBits/8])
+}
@@ -0,0 +1,50 @@
+package okamotoUchiyama
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrWeakRandSource = errors.New("okamoto-uchiyama: random source failed the health check")
+
+// randCheckSize is the byte length of each block CheckRandSource reads.
+const randCheckSize = 64
+
+// CheckRandSource reads two blocks from random and reports ErrWeakRandSource
+// if they are equal, repeat within themselves or consist of a single byte
+// value, as a broken or stuck source would produce. It is meant to run
+// before GenerateKey trusts a source. This is a sanity check that catches
+// gross failures only, not a statistical test of the randomness; a source
+// that passes can still be predictable.
+func CheckRandSource(random io.Reader) error {
+	a := make([]byte, randCheckSize)
+	b := make([]byte, randCheckSize)
+	if _, err := io.ReadFull(random, a); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(random, b); err != nil {
+		return err
+	}
+
+	if bytes.Equal(a, b) {
+		return ErrWeakRandSource
+	}
+	for _, block := range [][]byte{a, b} {
+		if bytes.Count(block, block[:1]) == len(block) || repeats(block) {
+			return ErrWeakRandSource
+		}
+	}
+	return nil
+}
+
+// repeats reports whether block is periodic with a period of at most half its
+// length.
+func repeats(block []byte) bool {
+	for period := 1; period <= len(block)/2; period++ {
+		if bytes.Equal(block[period:], block[:len(block)-period]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"math/bits"
+)
+
+var ErrPrimeSize = errors.New("okamoto-uchiyama: prime size too small")
+
+// smallPrimeBound bounds the primes used for trial division of candidates.
+const smallPrimeBound = 1024
+
+// smallPrimeGroup is a product of small odd primes that fits in a uint64,
+// so a candidate is reduced by one big division per group.
+type smallPrimeGroup struct {
+	product *big.Int
+	primes  []uint64
+}
+
+var smallPrimeGroups = groupSmallPrimes(smallPrimeBound)
+
+// groupSmallPrimes returns the odd primes below bound in uint64 groups.
+func groupSmallPrimes(bound uint64) []smallPrimeGroup {
+	composite := make([]bool, bound)
+	var groups []smallPrimeGroup
+	var product uint64 = 1
+	var primes []uint64
+	for s := uint64(3); s < bound; s += 2 {
+		if composite[s] {
+			continue
+		}
+		for c := s * s; c < bound; c += 2 * s {
+			composite[c] = true
+		}
+
+		if hi, _ := bits.Mul64(product, s); hi != 0 {
+			groups = append(groups, smallPrimeGroup{new(big.Int).SetUint64(product), primes})
+			product, primes = 1, nil
+		}
+		product *= s
+		primes = append(primes, s)
+	}
+	return append(groups, smallPrimeGroup{new(big.Int).SetUint64(product), primes})
+}
+
+// hasSmallFactor reports whether x is divisible by a small odd prime. x
+// must be larger than smallPrimeBound.
+func hasSmallFactor(x *big.Int) bool {
+	r := new(big.Int)
+	for _, g := range smallPrimeGroups {
+		m := r.Mod(x, g.product).Uint64()
+		for _, s := range g.primes {
+			if m%s == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// safePrime returns a safe prime p = 2q + 1 of the given bit length. Each
+// random candidate q is trial divided by the small primes, together with
+// 2q + 1, before any Miller-Rabin test, which discards most pairs cheaply.
+// The top two bits of p are set, like those of rand.Prime.
+func safePrime(random io.Reader, size int) (*big.Int, error) {
+	if size < 16 {
+		return nil, ErrPrimeSize
+	}
+
+	b := make([]byte, (size-1+7)/8)
+	q := new(big.Int)
+	p := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return nil, err
+		}
+
+		// q has size-1 bits with the top two set, and is odd
+		b[0] &= 0xff >> uint(len(b)*8-(size-1))
+		q.SetBytes(b)
+		q.SetBit(q, size-2, 1)
+		q.SetBit(q, size-3, 1)
+		q.SetBit(q, 0, 1)
+
+		// p = 2q + 1
+		p.Lsh(q, 1)
+		p.Add(p, one)
+		if hasSmallFactor(q) || hasSmallFactor(p) {
+			continue
+		}
+		if q.ProbablyPrime(20) && p.ProbablyPrime(20) {
+			return new(big.Int).Set(p), nil
+		}
+	}
+}
+
+// readerPrime returns a prime of the given bit length like rand.Prime, but is
+// guaranteed to draw its candidates from random only, so a deterministic
+// reader always yields the same prime. The top two bits are set.
+func readerPrime(random io.Reader, size int) (*big.Int, error) {
+	if size < 16 {
+		return nil, ErrPrimeSize
+	}
+
+	b := make([]byte, (size+7)/8)
+	p := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return nil, err
+		}
+
+		// p has size bits with the top two set, and is odd
+		b[0] &= 0xff >> uint(len(b)*8-size)
+		p.SetBytes(b)
+		p.SetBit(p, size-1, 1)
+		p.SetBit(p, size-2, 1)
+		p.SetBit(p, 0, 1)
+
+		if !hasSmallFactor(p) && p.ProbablyPrime(20) {
+			return new(big.Int).Set(p), nil
+		}
+	}
+}
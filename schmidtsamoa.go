@@ -0,0 +1,38 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrIncompatibleKey = errors.New("okamoto-uchiyama: N is not invertible mod lcm(p-1, q-1), no Schmidt-Samoa key exists")
+
+// ToSchmidtSamoaParams converts the key to the parameters of a Schmidt-Samoa
+// key over the same modulus. Both schemes use N = p^2 * q, which is also the
+// Schmidt-Samoa public key, encrypting c = m^N mod N. Its private key is
+// d = N^(-1) mod lcm(p-1, q-1) together with pq, decrypting m = c^d mod pq.
+// The generators G and H have no Schmidt-Samoa counterpart, and ciphers of
+// one scheme do not decrypt under the other. It returns ErrIncompatibleKey if
+// N shares a factor with lcm(p-1, q-1), i.e. p divides q-1 or q divides p-1.
+// Since both keys reveal the same factorization, a compromise of either
+// breaks both, so the modulus should only be shared between schemes
+// deliberately.
+func (priv *PrivateKey) ToSchmidtSamoaParams() (n, d, pq *big.Int, err error) {
+	q := priv.Q()
+	if q == nil || !positive(priv.P) {
+		return nil, nil, nil, ErrInvalidKey
+	}
+
+	// lcm(p-1, q-1) = (p-1)(q-1) / gcd(p-1, q-1)
+	pminuse1 := new(big.Int).Sub(priv.P, one)
+	qminuse1 := new(big.Int).Sub(q, one)
+	lcm := new(big.Int).Mul(pminuse1, qminuse1)
+	lcm.Div(lcm, new(big.Int).GCD(nil, nil, pminuse1, qminuse1))
+
+	// d = N^(-1) mod lcm(p-1, q-1)
+	d = new(big.Int).ModInverse(priv.N, lcm)
+	if d == nil {
+		return nil, nil, nil, ErrIncompatibleKey
+	}
+	return new(big.Int).Set(priv.N), d, new(big.Int).Mul(priv.P, q), nil
+}
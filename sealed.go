@@ -0,0 +1,138 @@
+package okamotoUchiyama
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+)
+
+var ErrKeyMismatch = errors.New("okamoto-uchiyama: cipher was sealed under a different key")
+var ErrInvalidSealed = errors.New("okamoto-uchiyama: invalid sealed cipher encoding")
+var ErrAuthenticationFailed = errors.New("okamoto-uchiyama: cipher failed authentication")
+
+// FingerprintSize is the byte length of a public key fingerprint.
+const FingerprintSize = sha256.Size
+
+// Fingerprint returns SHA-256 over the DER encoding of N, G and H, which
+// identifies the public key independently of its PlaintextBound.
+func (pub *PublicKey) Fingerprint() ([]byte, error) {
+	der, err := asn1.Marshal(publicKeyASN1{
+		N: pub.N,
+		G: pub.G,
+		H: pub.H,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return sum[:], nil
+}
+
+// SealedCiphertext pairs a fixed-width cipher with the fingerprint of the
+// public key it was encrypted under.
+type SealedCiphertext struct {
+	Fingerprint []byte
+	Cipher      []byte
+}
+
+// Bytes encodes the sealed cipher as the fingerprint followed by the cipher.
+func (s *SealedCiphertext) Bytes() []byte {
+	return append(append([]byte{}, s.Fingerprint...), s.Cipher...)
+}
+
+// ParseSealedCiphertext decodes a sealed cipher produced by Bytes.
+func ParseSealedCiphertext(b []byte) (*SealedCiphertext, error) {
+	if len(b) <= FingerprintSize {
+		return nil, ErrInvalidSealed
+	}
+	return &SealedCiphertext{
+		Fingerprint: append([]byte{}, b[:FingerprintSize]...),
+		Cipher:      append([]byte{}, b[FingerprintSize:]...),
+	}, nil
+}
+
+// EncryptSealed encrypts a plain text like Encrypt and seals the fixed-width
+// cipher with the fingerprint of the public key.
+func (pub *PublicKey) EncryptSealed(plainText []byte) (*SealedCiphertext, error) {
+	fingerprint, err := pub.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := pub.Encrypt(plainText)
+	if err != nil {
+		return nil, err
+	}
+	fixed, err := pub.fixedWidth(cipher)
+	if err != nil {
+		return nil, err
+	}
+	return &SealedCiphertext{Fingerprint: fingerprint, Cipher: fixed}, nil
+}
+
+// DecryptSealed decrypts a sealed cipher. It returns ErrKeyMismatch if the
+// cipher was not sealed under the public key of priv.
+func (priv *PrivateKey) DecryptSealed(sealed *SealedCiphertext) ([]byte, error) {
+	fingerprint, err := priv.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(fingerprint, sealed.Fingerprint) != 1 {
+		return nil, ErrKeyMismatch
+	}
+	return priv.Decrypt(sealed.Cipher)
+}
+
+// authenticatedLabel separates the MAC of SealAuthenticated.
+var authenticatedLabel = []byte("okamoto-uchiyama authenticated")
+
+// authenticationTag returns HMAC-SHA256 under macKey over the label, the key
+// fingerprint and the fixed-width cipher.
+func authenticationTag(macKey, fingerprint, fixed []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(authenticatedLabel)
+	mac.Write(fingerprint)
+	mac.Write(fixed)
+	return mac.Sum(nil)
+}
+
+// SealAuthenticated encrypts a plain text like Encrypt and appends an
+// HMAC-SHA256 tag under the caller's secret macKey to the fixed-width cipher,
+// binding it to the public key. OpenAuthenticated rejects any modification,
+// which also rejects every homomorphically derived cipher, so sealed ciphers
+// give up the homomorphic property; ciphers must be combined before sealing.
+func (pub *PublicKey) SealAuthenticated(plain, macKey []byte) ([]byte, error) {
+	fingerprint, err := pub.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := pub.Encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+	fixed, err := pub.fixedWidth(cipher)
+	if err != nil {
+		return nil, err
+	}
+	return append(fixed, authenticationTag(macKey, fingerprint, fixed)...), nil
+}
+
+// OpenAuthenticated verifies the tag of a cipher produced by
+// SealAuthenticated with macKey and decrypts it. It returns
+// ErrAuthenticationFailed if the tag does not match, without decrypting.
+func (priv *PrivateKey) OpenAuthenticated(sealed, macKey []byte) ([]byte, error) {
+	size := priv.ciphertextSize()
+	if len(sealed) != size+sha256.Size {
+		return nil, ErrAuthenticationFailed
+	}
+	fingerprint, err := priv.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	fixed, tag := sealed[:size], sealed[size:]
+	if !hmac.Equal(tag, authenticationTag(macKey, fingerprint, fixed)) {
+		return nil, ErrAuthenticationFailed
+	}
+	return priv.Decrypt(fixed)
+}
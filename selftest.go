@@ -0,0 +1,104 @@
+package okamotoUchiyama
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+var ErrSelfTest = errors.New("okamoto-uchiyama: self-test failed")
+
+// selfTestBits is the key size of SelfTest, small to keep it fast.
+const selfTestBits = 256
+
+// SelfTest is a power-on self-test of the package. It generates a small key
+// from Rand, encrypts and decrypts a known value, adds two ciphers
+// homomorphically and checks the results, returning ErrSelfTest on any
+// mismatch. Deployments that want it can run it before first use, e.g.
+//
+//	func init() {
+//		if err := okamotoUchiyama.SelfTest(); err != nil {
+//			panic(err)
+//		}
+//	}
+func SelfTest() error {
+	priv, err := GenerateKey(Rand, selfTestBits)
+	if err != nil {
+		return err
+	}
+	pub := &priv.PublicKey
+
+	a, b := big.NewInt(1234), big.NewInt(4321)
+	ca, err := pub.Encrypt(a.Bytes())
+	if err != nil {
+		return err
+	}
+	cb, err := pub.Encrypt(b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	m, err := priv.decrypt(ca)
+	if err != nil {
+		return err
+	}
+	if m.Cmp(a) != 0 {
+		return ErrSelfTest
+	}
+
+	sum, err := pub.HomomorphicEncTwo(ca, cb)
+	if err != nil {
+		return err
+	}
+	m, err = priv.decrypt(sum)
+	if err != nil {
+		return err
+	}
+	if m.Cmp(new(big.Int).Add(a, b)) != 0 {
+		return ErrSelfTest
+	}
+	return nil
+}
+
+// SelfCheckHomomorphic checks the key itself after generation or import: it
+// encrypts two random values below half the plaintext bound, adds the
+// ciphers homomorphically and returns ErrSelfTest unless the sum decrypts
+// correctly. Validate checks the key values against each other, while this
+// check runs encryption, homomorphic addition and decryption end to end,
+// including any decryption values cached by Precompute.
+func (priv *PrivateKey) SelfCheckHomomorphic() error {
+	if !positive(priv.P) {
+		return ErrInvalidKey
+	}
+	half := new(big.Int).Rsh(plaintextBound(priv.P), 1)
+	a, err := rand.Int(Rand, half)
+	if err != nil {
+		return err
+	}
+	b, err := rand.Int(Rand, half)
+	if err != nil {
+		return err
+	}
+
+	pub := &priv.PublicKey
+	ca, err := pub.Encrypt(a.Bytes())
+	if err != nil {
+		return err
+	}
+	cb, err := pub.Encrypt(b.Bytes())
+	if err != nil {
+		return err
+	}
+	sum, err := pub.HomomorphicEncTwo(ca, cb)
+	if err != nil {
+		return err
+	}
+	m, err := priv.decrypt(sum)
+	if err != nil {
+		return err
+	}
+	if m.Cmp(new(big.Int).Add(a, b)) != 0 {
+		return ErrSelfTest
+	}
+	return nil
+}
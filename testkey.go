@@ -0,0 +1,27 @@
+package okamotoUchiyama
+
+import "math/big"
+
+// Parameters of the fixed key returned by GenerateTestKey. Both primes are
+// 256-bit safe primes.
+const (
+	testKeyP = "c7fbd2560018a6e1a32011d0011ca1a9e6aa1f24f3c82c511cbd20c33f02cc37"
+	testKeyQ = "c1cd393ba46defb35d948cc34931b5445e512d948f164b8400c2cdad9d19826f"
+	testKeyG = "2"
+)
+
+// GenerateTestKey returns a fixed, embedded private key built from small
+// safe primes without any prime generation, for tests that only need a valid
+// key structure. The key is public knowledge and far too small: it is
+// INSECURE and must never protect real data. Each call returns a fresh copy.
+func GenerateTestKey() *PrivateKey {
+	p, _ := new(big.Int).SetString(testKeyP, 16)
+	q, _ := new(big.Int).SetString(testKeyQ, 16)
+	g, _ := new(big.Int).SetString(testKeyG, 16)
+
+	priv, err := GenerateKeyFromPrimes(p, q, g)
+	if err != nil {
+		panic("okamoto-uchiyama: invalid embedded test key: " + err.Error())
+	}
+	return priv
+}
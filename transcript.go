@@ -0,0 +1,126 @@
+package okamotoUchiyama
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+)
+
+var ErrInvalidTranscript = errors.New("okamoto-uchiyama: invalid transcript step")
+var ErrTranscriptMismatch = errors.New("okamoto-uchiyama: transcript result does not match its replay")
+var ErrTranscriptNoKey = errors.New("okamoto-uchiyama: transcript has no key to record with")
+
+// OpAddConstant names the addition of a public constant in a Transcript.
+// Transcripts name the other operations OpAdd and OpScalarMul.
+const OpAddConstant = "const-add"
+
+// TranscriptStep is one homomorphic operation of a Transcript. Operands index
+// the values of the transcript: the inputs first, followed by the results of
+// the earlier steps in order.
+type TranscriptStep struct {
+	Op       string   `json:"op"`
+	Operands []int    `json:"operands"`
+	Scalar   *big.Int `json:"scalar,omitempty"`
+	Result   []byte   `json:"result"`
+}
+
+// Transcript records the homomorphic operations applied to a set of input
+// ciphers, so that a verifier holding the public key can replay them and
+// re-derive the final cipher. It serializes to JSON with encoding/json. The
+// key is not serialized, so a decoded transcript only supports Replay(pub).
+type Transcript struct {
+	Inputs [][]byte         `json:"inputs"`
+	Steps  []TranscriptStep `json:"steps"`
+
+	pub *PublicKey
+}
+
+// NewTranscript starts a transcript of operations under pub on the passed
+// input ciphers.
+func NewTranscript(pub *PublicKey, inputs ...[]byte) *Transcript {
+	t := &Transcript{pub: pub}
+	for _, c := range inputs {
+		t.Inputs = append(t.Inputs, append([]byte{}, c...))
+	}
+	return t
+}
+
+// Record applies the operation op to the operands with the public scalar,
+// which OpScalarMul and OpAddConstant need and OpAdd ignores, and records
+// the step. OpAdd sums any number of operands, the others take exactly one.
+// The result is returned and can be used as operand len(Inputs)+len(Steps)-1.
+// It returns ErrTranscriptNoKey for a transcript not made by NewTranscript,
+// e.g. one decoded from JSON.
+func (t *Transcript) Record(op string, operands []int, scalar *big.Int) ([]byte, error) {
+	if t.pub == nil {
+		return nil, ErrTranscriptNoKey
+	}
+	result, err := applyStep(t.pub, t.values(), op, operands, scalar)
+	if err != nil {
+		return nil, err
+	}
+
+	step := TranscriptStep{
+		Op:       op,
+		Operands: append([]int{}, operands...),
+		Result:   result,
+	}
+	if op != OpAdd {
+		step.Scalar = new(big.Int).Set(scalar)
+	}
+	t.Steps = append(t.Steps, step)
+	return result, nil
+}
+
+// Replay recomputes every step of the transcript under pub and returns the
+// result of the last one. It returns ErrTranscriptMismatch if a recorded
+// result differs from its recomputation, and ErrInvalidTranscript if the
+// transcript has no steps or a malformed one.
+func (t *Transcript) Replay(pub *PublicKey) ([]byte, error) {
+	if len(t.Steps) == 0 {
+		return nil, ErrInvalidTranscript
+	}
+
+	values := append([][]byte{}, t.Inputs...)
+	for _, step := range t.Steps {
+		result, err := applyStep(pub, values, step.Op, step.Operands, step.Scalar)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(result, step.Result) {
+			return nil, ErrTranscriptMismatch
+		}
+		values = append(values, result)
+	}
+	return values[len(values)-1], nil
+}
+
+// values returns the inputs followed by the recorded results.
+func (t *Transcript) values() [][]byte {
+	values := append([][]byte{}, t.Inputs...)
+	for _, step := range t.Steps {
+		values = append(values, step.Result)
+	}
+	return values
+}
+
+// applyStep computes one transcript step on values under pub.
+func applyStep(pub *PublicKey, values [][]byte, op string, operands []int, scalar *big.Int) ([]byte, error) {
+	ciphers := make([][]byte, len(operands))
+	for i, index := range operands {
+		if index < 0 || index >= len(values) {
+			return nil, ErrInvalidTranscript
+		}
+		ciphers[i] = values[index]
+	}
+
+	switch {
+	case op == OpAdd && len(ciphers) > 0:
+		return pub.HommorphicEncMultiple(ciphers...)
+	case op == OpScalarMul && len(ciphers) == 1 && scalar != nil:
+		return pub.HomomorphicScalarMul(ciphers[0], scalar)
+	case op == OpAddConstant && len(ciphers) == 1 && scalar != nil:
+		return pub.HomomorphicAddConstant(ciphers[0], scalar)
+	}
+	return nil, ErrInvalidTranscript
+}
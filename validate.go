@@ -0,0 +1,175 @@
+package okamotoUchiyama
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrSquaredPrimeMismatch = errors.New("okamoto-uchiyama: P is not the squared prime of N")
+
+// smallOrderBound is the largest element order Validate rejects for sure.
+const smallOrderBound = 1024
+
+// smallOrderExponent is lcm(1...smallOrderBound). An element x has an order
+// dividing it, and in particular any order up to smallOrderBound, exactly
+// when x^smallOrderExponent = 1.
+var smallOrderExponent = lcmUpTo(smallOrderBound)
+
+// lcmUpTo returns lcm(1...bound) as the product of the largest powers of
+// all primes up to bound.
+func lcmUpTo(bound int64) *big.Int {
+	l := big.NewInt(1)
+	for p := int64(2); p <= bound; p++ {
+		if !big.NewInt(p).ProbablyPrime(0) {
+			continue
+		}
+		pk := p
+		for pk*p <= bound {
+			pk *= p
+		}
+		l.Mul(l, big.NewInt(pk))
+	}
+	return l
+}
+
+// Validate checks that the public key is plausible: N > 1, G and H lie in
+// {2...N-1} and are coprime with N, and neither has a small order, which
+// would make g^m or the blinding h^r take only a few values. An element is
+// rejected if its order divides lcm(1...1024), which covers every order up to
+// 1024. Without the factorization of N the exact order of H cannot be
+// computed, so this does not prove that H generates a large subgroup; keys
+// from untrusted sources remain only as trustworthy as their origin. It
+// returns ErrInvalidKey if a check fails.
+func (pub *PublicKey) Validate() error {
+	if !positive(pub.N, pub.G, pub.H) || pub.N.Cmp(one) <= 0 {
+		return ErrInvalidKey
+	}
+
+	for _, x := range []*big.Int{pub.G, pub.H} {
+		if x.Cmp(one) <= 0 || x.Cmp(pub.N) >= 0 {
+			return ErrInvalidKey
+		}
+		if new(big.Int).GCD(nil, nil, x, pub.N).Cmp(one) != 0 {
+			return ErrInvalidKey
+		}
+		if new(big.Int).Exp(x, smallOrderExponent, pub.N).Cmp(one) == 0 {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// checkSquaredPrime reports whether P is the prime whose square divides N,
+// as the scheme requires for N = p^2 * q.
+func (priv *PrivateKey) checkSquaredPrime() error {
+	if !positive(priv.N, priv.P, priv.PSquared) {
+		return ErrSquaredPrimeMismatch
+	}
+	if new(big.Int).Mul(priv.P, priv.P).Cmp(priv.PSquared) != 0 {
+		return ErrSquaredPrimeMismatch
+	}
+	if new(big.Int).Mod(priv.N, priv.PSquared).Sign() != 0 {
+		return ErrSquaredPrimeMismatch
+	}
+	return nil
+}
+
+// NormalizeSquaredPrime fixes a key imported with the roles of the primes
+// mixed up, e.g. P holding the prime q that appears only once in N. It finds
+// the squared prime from PSquared, or from N / P, sets P and PSquared to it
+// and recomputes GD, the plaintext bound and its commitment. It returns
+// ErrSquaredPrimeMismatch if no such prime is found.
+func (priv *PrivateKey) NormalizeSquaredPrime() error {
+	if priv.checkSquaredPrime() == nil {
+		return nil
+	}
+	if !positive(priv.N, priv.G) {
+		return ErrSquaredPrimeMismatch
+	}
+
+	var candidates []*big.Int
+	if priv.PSquared != nil && priv.PSquared.Sign() > 0 {
+		// PSquared holds p^2
+		candidates = append(candidates, new(big.Int).Sqrt(priv.PSquared))
+	}
+	if priv.P != nil && priv.P.Sign() > 0 {
+		// P holds q, so N / P = p^2
+		if other, rem := new(big.Int).QuoRem(priv.N, priv.P, new(big.Int)); rem.Sign() == 0 {
+			candidates = append(candidates, new(big.Int).Sqrt(other))
+		}
+	}
+
+	for _, p := range candidates {
+		psquare := new(big.Int).Mul(p, p)
+		if p.Cmp(one) <= 0 || new(big.Int).Mod(priv.N, psquare).Sign() != 0 || !p.ProbablyPrime(20) {
+			continue
+		}
+		priv.P = p
+		priv.PSquared = psquare
+		priv.GD = priv.ComputeGD()
+		priv.PlaintextBound = plaintextBound(p)
+		priv.recommitPlaintextBound()
+		priv.precomputed = nil
+		return nil
+	}
+	return ErrSquaredPrimeMismatch
+}
+
+// LooksFermatWeak reports whether rounds steps of a Fermat-style search split
+// N, which catches moduli with badly chosen primes. Classic Fermat
+// factorization only finds divisor pairs near sqrt(N), which for N = p^2 * q
+// is the pair (q, p^2) and only close if q is near p^2. Primes p and q close
+// to each other instead put p near the cube root of N, since
+// N^(1/3) = p * (q/p)^(1/3), so the divisors around the cube root are tried
+// as well. A false result does not prove the key strong.
+func (pub *PublicKey) LooksFermatWeak(rounds int) bool {
+	if !positive(pub.N) || pub.N.Cmp(one) <= 0 {
+		return false
+	}
+	rem := new(big.Int)
+
+	// a = ceil(sqrt(N)), tried until a^2 - N = b^2 with a - b > 1
+	a := new(big.Int).Sqrt(pub.N)
+	if new(big.Int).Mul(a, a).Cmp(pub.N) < 0 {
+		a.Add(a, one)
+	}
+	for i := 0; i < rounds; i++ {
+		b2 := new(big.Int).Sub(new(big.Int).Mul(a, a), pub.N)
+		b := new(big.Int).Sqrt(b2)
+		if new(big.Int).Mul(b, b).Cmp(b2) == 0 && new(big.Int).Sub(a, b).Cmp(one) > 0 {
+			return true
+		}
+		a.Add(a, one)
+	}
+
+	// x = cbrt(N) +- i, tried as divisors of N
+	t := cubeRoot(pub.N)
+	for i := 0; i < rounds; i++ {
+		for _, x := range []*big.Int{
+			new(big.Int).Add(t, big.NewInt(int64(i))),
+			new(big.Int).Sub(t, big.NewInt(int64(i))),
+		} {
+			if x.Cmp(one) > 0 && x.Cmp(pub.N) < 0 && rem.Mod(pub.N, x).Sign() == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cubeRoot returns floor(n^(1/3)) for n > 0 by Newton's iteration, starting
+// above the root.
+func cubeRoot(n *big.Int) *big.Int {
+	x := new(big.Int).Lsh(one, uint((n.BitLen()+2)/3))
+	three := big.NewInt(3)
+	for {
+		// y = (2x + n / x^2) / 3
+		y := new(big.Int).Div(n, new(big.Int).Mul(x, x))
+		y.Add(y, new(big.Int).Lsh(x, 1))
+		y.Div(y, three)
+		if y.Cmp(x) >= 0 {
+			return x
+		}
+		x = y
+	}
+}
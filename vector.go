@@ -0,0 +1,108 @@
+package okamotoUchiyama
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+var ErrMalformedVector = errors.New("okamoto-uchiyama: malformed encrypted vector")
+var ErrVectorLength = errors.New("okamoto-uchiyama: encrypted vectors differ in length")
+
+// encodeVector frames the ciphers into one blob: a 4-byte big-endian count,
+// then each cipher as a 4-byte big-endian length and the cipher bytes.
+func encodeVector(ciphers [][]byte) []byte {
+	blob := binary.BigEndian.AppendUint32(nil, uint32(len(ciphers)))
+	for _, c := range ciphers {
+		blob = binary.BigEndian.AppendUint32(blob, uint32(len(c)))
+		blob = append(blob, c...)
+	}
+	return blob
+}
+
+// decodeVector splits a blob produced by encodeVector into its ciphers.
+func decodeVector(blob []byte) ([][]byte, error) {
+	if len(blob) < 4 {
+		return nil, ErrMalformedVector
+	}
+	count := binary.BigEndian.Uint32(blob)
+	blob = blob[4:]
+	if uint64(count) > uint64(len(blob)/4) { // every cipher needs a length
+		return nil, ErrMalformedVector
+	}
+
+	ciphers := make([][]byte, count)
+	for i := range ciphers {
+		if len(blob) < 4 {
+			return nil, ErrMalformedVector
+		}
+		n := binary.BigEndian.Uint32(blob)
+		blob = blob[4:]
+		if uint64(n) > uint64(len(blob)) {
+			return nil, ErrMalformedVector
+		}
+		ciphers[i] = blob[:n]
+		blob = blob[n:]
+	}
+	if len(blob) != 0 {
+		return nil, ErrMalformedVector
+	}
+	return ciphers, nil
+}
+
+// EncryptVector encrypts each value and frames the fixed-width ciphers into
+// one blob. It returns ErrLargeMessage if a value does not fit.
+func (pub *PublicKey) EncryptVector(values []*big.Int) ([]byte, error) {
+	ciphers := make([][]byte, len(values))
+	for i, v := range values {
+		if !pub.PlaintextFits(v) {
+			return nil, ErrLargeMessage
+		}
+		c, err := pub.Encrypt(v.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if ciphers[i], err = pub.fixedWidth(c); err != nil {
+			return nil, err
+		}
+	}
+	return encodeVector(ciphers), nil
+}
+
+// DecryptVector decrypts each element of a blob produced by EncryptVector.
+func (priv *PrivateKey) DecryptVector(blob []byte) ([]*big.Int, error) {
+	ciphers, err := decodeVector(blob)
+	if err != nil {
+		return nil, err
+	}
+	return priv.DecryptMulti(ciphers)
+}
+
+// AddVectors adds two encrypted vectors element-wise. The resultant blob
+// contains a1+b1, a2+b2, ..., an+bn. It returns ErrVectorLength if the
+// vectors differ in length.
+func (pub *PublicKey) AddVectors(a, b []byte) ([]byte, error) {
+	ciphersA, err := decodeVector(a)
+	if err != nil {
+		return nil, err
+	}
+	ciphersB, err := decodeVector(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphersA) != len(ciphersB) {
+		return nil, ErrVectorLength
+	}
+
+	sums := make([][]byte, len(ciphersA))
+	for i := range ciphersA {
+		C, err := pub.HomomorphicEncTwo(ciphersA[i], ciphersB[i])
+		if err != nil {
+			return nil, err
+		}
+		if sums[i], err = pub.fixedWidth(C); err != nil {
+			return nil, err
+		}
+	}
+	return encodeVector(sums), nil
+}
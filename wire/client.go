@@ -0,0 +1,70 @@
+package wire
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	okamotoUchiyama "github.com/Mirzazhar/okamoto-uchiyama"
+)
+
+// Client sends requests to a Server over one connection. Encryption happens
+// locally with the public key it holds. It is safe for concurrent use; the
+// requests are serialized on the connection.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	pub  *okamotoUchiyama.PublicKey
+}
+
+// NewClient returns a client talking over conn with the passed public key.
+func NewClient(conn net.Conn, pub *okamotoUchiyama.PublicKey) *Client {
+	return &Client{conn: conn, pub: pub}
+}
+
+// Encrypt encrypts the plain text locally with the public key.
+func (c *Client) Encrypt(plainText []byte) ([]byte, error) {
+	return c.pub.Encrypt(plainText)
+}
+
+// RemoteEncrypt asks the server to encrypt the plain text.
+func (c *Client) RemoteEncrypt(plainText []byte) ([]byte, error) {
+	return c.call(OpEncrypt, plainText)
+}
+
+// Decrypt asks the server to decrypt the cipher text.
+func (c *Client) Decrypt(cipherText []byte) ([]byte, error) {
+	return c.call(OpDecrypt, cipherText)
+}
+
+// Add asks the server to homomorphically add the passed ciphers.
+func (c *Client) Add(ciphers ...[]byte) ([]byte, error) {
+	return c.call(OpAdd, ciphers...)
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// call sends one request and waits for its response.
+func (c *Client) call(op byte, fields ...[]byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeFrame(c.conn, encodeRequest(op, fields...)); err != nil {
+		return nil, err
+	}
+	response, err := readFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) == 0 {
+		return nil, ErrMalformedMessage
+	}
+	if response[0] != statusOK {
+		return nil, errors.New("okamoto-uchiyama/wire: remote: " + string(response[1:]))
+	}
+	return response[1:], nil
+}
@@ -0,0 +1,84 @@
+package wire
+
+import (
+	"errors"
+	"io"
+	"net"
+
+	okamotoUchiyama "github.com/Mirzazhar/okamoto-uchiyama"
+)
+
+// Server answers encrypt, decrypt and homomorphic-add requests with the
+// private key it holds.
+type Server struct {
+	priv *okamotoUchiyama.PrivateKey
+}
+
+// NewServer returns a server holding the passed private key.
+func NewServer(priv *okamotoUchiyama.PrivateKey) *Server {
+	return &Server{priv: priv}
+}
+
+// Serve accepts connections on l and serves each of them in its own
+// goroutine. It returns the error that stopped accepting.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			s.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn answers requests read from conn until the peer closes it. It
+// returns nil on a clean close and the read or write error otherwise.
+// Failing operations are reported to the peer and do not end the connection.
+func (s *Server) ServeConn(conn net.Conn) error {
+	for {
+		payload, err := readFrame(conn)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := s.handle(payload)
+		response := append([]byte{statusOK}, result...)
+		if err != nil {
+			response = append([]byte{statusError}, err.Error()...)
+		}
+		if err := writeFrame(conn, response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle performs the operation of one request payload.
+func (s *Server) handle(payload []byte) ([]byte, error) {
+	op, fields, err := decodeRequest(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case OpEncrypt:
+		if len(fields) != 1 {
+			return nil, ErrMalformedMessage
+		}
+		return s.priv.Encrypt(fields[0])
+	case OpDecrypt:
+		if len(fields) != 1 {
+			return nil, ErrMalformedMessage
+		}
+		return s.priv.Decrypt(fields[0])
+	case OpAdd:
+		return s.priv.HommorphicEncMultiple(fields...)
+	default:
+		return nil, ErrUnknownOp
+	}
+}
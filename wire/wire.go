@@ -0,0 +1,100 @@
+// Package wire provides a minimal length-prefixed protocol to encrypt,
+// decrypt and homomorphically add Okamoto-Uchiyama ciphers over a net.Conn.
+// The Server holds the private key and the Client holds the public key.
+//
+// Every message is a frame of a 4-byte big-endian length followed by the
+// payload. A request payload is an operation byte followed by its fields,
+// each a 4-byte big-endian length and the field bytes. A response payload
+// is a status byte followed by the result, or by the error text if the
+// status is not ok.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Operations of a request.
+const (
+	OpEncrypt byte = iota + 1
+	OpDecrypt
+	OpAdd
+)
+
+// Status of a response.
+const (
+	statusOK byte = iota
+	statusError
+)
+
+// MaxMessageSize is the largest accepted frame payload.
+const MaxMessageSize = 1 << 20
+
+var ErrMessageTooLarge = errors.New("okamoto-uchiyama/wire: message is larger than MaxMessageSize")
+var ErrMalformedMessage = errors.New("okamoto-uchiyama/wire: malformed message")
+var ErrUnknownOp = errors.New("okamoto-uchiyama/wire: unknown operation")
+
+// writeFrame writes payload as one length-prefixed frame.
+func writeFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads the payload of one length-prefixed frame.
+func readFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// encodeRequest encodes an operation and its fields as a request payload.
+func encodeRequest(op byte, fields ...[]byte) []byte {
+	payload := []byte{op}
+	for _, field := range fields {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(field)))
+		payload = append(payload, field...)
+	}
+	return payload
+}
+
+// decodeRequest decodes a request payload into its operation and fields.
+func decodeRequest(payload []byte) (byte, [][]byte, error) {
+	if len(payload) == 0 {
+		return 0, nil, ErrMalformedMessage
+	}
+
+	op, rest := payload[0], payload[1:]
+	var fields [][]byte
+	for len(rest) > 0 {
+		if len(rest) < 4 {
+			return 0, nil, ErrMalformedMessage
+		}
+		n := binary.BigEndian.Uint32(rest)
+		rest = rest[4:]
+		if uint64(n) > uint64(len(rest)) {
+			return 0, nil, ErrMalformedMessage
+		}
+		fields = append(fields, rest[:n])
+		rest = rest[n:]
+	}
+	return op, fields, nil
+}